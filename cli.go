@@ -0,0 +1,397 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/murphybytes/shapes/search"
+)
+
+// Exit codes tell scripts why the program stopped.
+const (
+	exitOK = iota
+	// exitFailure is a search that failed or output that could not be written.
+	exitFailure
+	// exitUsage is an unknown command, flag or flag value.
+	exitUsage
+	// exitParse is input that could not be read.
+	exitParse
+	// exitCancelled is a user who cancelled at a prompt or interrupted the search.
+	exitCancelled
+)
+
+const errorCommand = errorType("unknown command")
+const errorArgs = errorType("wrong number of arguments")
+
+// usageError is an error in how the program was invoked.
+type usageError struct{ error }
+
+// inputError is an error in the input read, as opposed to one from the search or writing the output.
+type inputError struct{ error }
+
+// cli runs commands reading and writing the given streams.
+type cli struct {
+	stdin          io.Reader
+	stdout, stderr io.Writer
+}
+
+// command is a subcommand of the program. Run is given the command's flags, with help text already set.
+type command struct {
+	name, args, summary string
+	run                 func(c cli, fs *flag.FlagSet, args []string) error
+}
+
+// commands are set in init, as record and replay run commands themselves.
+var commands []command
+
+func init() {
+	commands = []command{
+		{"find", "", "find the unique shapes in a grid and write them out", cli.find},
+		{"count", "", "count the shapes in a grid and how often each unique shape occurs", cli.count},
+		{"render", "", "write a grid back out, converting formats or coloring its shapes", cli.render},
+		{"gen", "", "generate a random grid", cli.gen},
+		{"match", "", "count the occurrences in a grid of the shapes of a pattern", cli.match},
+		{"diff", "old new", "compare the unique shapes of two grids", cli.diff},
+		{"edit", "file", "draw a grid in the terminal, seeing its shapes as they are drawn", cli.edit},
+		{"record", "transcript command [flags]", "run a command, writing its prompts, answers and output to a transcript", cli.record},
+		{"replay", "transcript", "run a transcript's command again with its answers and check the output is the same", cli.replay},
+		{"serve", "", "answer searches over HTTP", cli.serve},
+	}
+}
+
+// run runs the command named by the first argument and returns the exit code. With no command, or flags first, it
+// runs find, so the program still works as it did before it had commands.
+func (c cli) run(args []string) int {
+	name := "find"
+	switch {
+	case len(args) == 0 || args[0] == "find":
+	case isHelp(args[0]) || args[0] == "help":
+		if len(args) > 1 {
+			return c.run([]string{args[1], "-help"})
+		}
+		c.usage(c.stdout)
+		return exitOK
+	case !strings.HasPrefix(args[0], "-"):
+		name = args[0]
+	}
+	if len(args) > 0 && args[0] == name {
+		args = args[1:]
+	}
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return c.exit(name, cmd.run(c, c.newFlags(cmd), args))
+		}
+	}
+	c.usage(c.stderr)
+	return c.exit(name, usageError{errorCommand})
+}
+
+// usage lists the commands.
+func (c cli) usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: shapes <command> [flags] [files]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-8s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run shapes <command> -help for the flags of a command. With no command shapes runs find.")
+}
+
+// exit reports an error and returns the exit code for it.
+func (c cli) exit(name string, err error) int {
+	if err == nil || err == flag.ErrHelp {
+		return exitOK
+	}
+	fmt.Fprintf(c.stderr, "shapes %s: %v\n", name, err)
+	switch err.(type) {
+	case usageError:
+		return exitUsage
+	case inputError:
+		return exitParse
+	}
+	if err == errorUserTerminated || err == context.Canceled {
+		return exitCancelled
+	}
+	return exitFailure
+}
+
+func isHelp(arg string) bool {
+	return arg == "-h" || arg == "-help" || arg == "--help"
+}
+
+// newFlags returns an empty set of flags for a command, with help text giving its arguments and summary before
+// the flags.
+func (c cli) newFlags(cmd command) *flag.FlagSet {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: shapes %s\n\n%s.\n\nflags:\n", strings.TrimSpace(cmd.name+" [flags] "+cmd.args), cmd.summary)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parse parses the flags of a command and checks it was given the number of other arguments it takes. Help asked
+// for goes to standard output.
+func (c cli) parse(fs *flag.FlagSet, args []string, n int) error {
+	for _, a := range args {
+		if isHelp(a) {
+			fs.SetOutput(c.stdout)
+			fs.Usage()
+			return flag.ErrHelp
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return usageError{err}
+	}
+	if fs.NArg() != n {
+		fs.Usage()
+		return usageError{errorArgs}
+	}
+	return nil
+}
+
+// oneOf returns a usage error unless the flag's value is one of the choices.
+func oneOf(name, v string, choices ...string) error {
+	for _, c := range choices {
+		if v == c {
+			return nil
+		}
+	}
+	return usageError{fmt.Errorf("-%s %q is not one of %s", name, v, strings.Join(choices, ", "))}
+}
+
+// textFlag is a flag holding a search option, set and shown by the option's name.
+type textFlag struct {
+	v interface {
+		encoding.TextUnmarshaler
+		fmt.Stringer
+	}
+}
+
+func (t textFlag) Set(s string) error { return t.v.UnmarshalText([]byte(s)) }
+
+func (t textFlag) String() string {
+	if t.v == nil {
+		return ""
+	}
+	return t.v.String()
+}
+
+// config holds the flags of the commands that read and search a grid.
+type config struct {
+	in       string
+	format   string
+	opts     search.Options
+	f        format
+	progress bool
+}
+
+// gridFormats are the input formats that hold a whole grid.
+var gridFormats = []string{"prompt", "paste", "text", "compact", "json", "image", "triangles", "hex"}
+
+// searchFlags adds the flags for reading a grid in any of the given formats, the first the default, and searching
+// it. Commands that read one input also take it from the -in flag.
+func searchFlags(fs *flag.FlagSet, in bool, formats ...string) *config {
+	cfg := &config{}
+	if in {
+		fs.StringVar(&cfg.in, "in", "-", "file to read, - for standard input")
+	}
+	help := "input format, one of:"
+	for _, f := range formats {
+		help += "\n  " + f + " " + formatHelp[f]
+	}
+	fs.StringVar(&cfg.format, "format", formats[0], help)
+	optionFlags(fs, &cfg.opts)
+	fs.Var(textFlag{&cfg.opts.Lattice}, "lattice", "shape of the cells, square, hex or triangle; hex rows are staggered with odd rows half a cell right")
+	fs.BoolVar(&cfg.f.labels, "labels", false, "accept any non-negative integer as a cell label, zero is empty space")
+	compactFlags(fs, &cfg.f)
+	fs.StringVar(&cfg.f.comment, "comment", "//", "start of the lines skipped in text, compact, stream and volume input, empty for none")
+	fs.Var(textFlag{&cfg.opts.Glyphs}, "glyphs", "characters that shapes are drawn with, one for set cells then one for empty cells")
+	fs.Var(textFlag{&cfg.opts.Preprocess}, "morph", "steps cleaning up the grid before the search, separated by commas: erode, dilate, open or close, each with an optional =element of cross, box, cross:N, box:N or rows of 0 and 1 separated by /, fill for holes, or min=N to drop shapes of fewer than N cells")
+	fs.Var(textFlag{&cfg.opts.Filter}, "filter", "conditions a shape must meet to be kept, separated by commas: cells, width, height or holes compared to a number with =, !=, <, <=, > or >=, or edge or wraps, either with ! before it, as cells>=4,!edge")
+	fs.BoolVar(&cfg.opts.Parallel, "parallel", false, "label bands of rows at the same time on every available CPU")
+	fs.BoolVar(&cfg.progress, "progress", false, "report search progress on standard error")
+	return cfg
+}
+
+// optionFlags adds the flags for the options that decide which cells make up shapes on a square grid and which
+// shapes are duplicates.
+func optionFlags(fs *flag.FlagSet, opts *search.Options) {
+	fs.Var(textFlag{&opts.Topology}, "topology", "surface of the grid, torus or plane")
+	fs.Var(textFlag{&opts.Connectivity}, "connectivity", "cells joined by an edge, 4, or also by a corner, 8")
+	fs.Var(textFlag{&opts.Equivalence}, "equivalence", "moves that make shapes duplicates, translation, rotation or reflection")
+	fs.BoolVar(&opts.AcrossValues, "across", false, "treat shapes with different labels but the same layout as duplicates")
+	fs.BoolVar(&opts.FillHoles, "fill-holes", false, "treat shapes as duplicates when they match with their holes filled in")
+}
+
+// compactFlags adds the flags for the characters of compact text.
+func compactFlags(fs *flag.FlagSet, f *format) {
+	fs.StringVar(&f.set, "set", "#", "characters that stand for set cells in compact text, besides digits; the first is written")
+	fs.StringVar(&f.unset, "unset", ".", "characters that stand for empty cells in compact text, besides digits; the first is written")
+}
+
+// formatHelp describes the input formats.
+var formatHelp = map[string]string{
+	"prompt":    "asks for the grid row by row",
+	"paste":     "asks for the whole grid at once, ended by a blank line, and shows it back to check",
+	"text":      "is rows of space separated values",
+	"compact":   "is rows with a character for each cell, a digit or one of the -set or -unset characters",
+	"json":      "is an object with a grid array of rows",
+	"image":     "is a PNG, GIF or JPEG with dark pixels set",
+	"triangles": "is rows drawn with ^, v and . for a triangle lattice",
+	"hex":       "is rows of space separated values with odd rows indented one space, as a hex lattice staggers them",
+	"points":    "is the column and row of each set cell of an unbounded plane, axial on a hex lattice",
+	"stream":    "is text read two rows at a time",
+	"volume":    "is text slices, each followed by a blank line",
+}
+
+// open returns the named file, or standard input for -.
+func (c cli) open(name string) (io.ReadCloser, error) {
+	if name == "-" {
+		return ioutil.NopCloser(c.stdin), nil
+	}
+	return os.Open(name)
+}
+
+// readGrid reads a grid in one of gridFormats. Prompts are written to w.
+func (cfg config) readGrid(r io.Reader, w io.Writer) ([][]int, error) {
+	var g [][]int
+	var err error
+	switch cfg.format {
+	case "prompt":
+		g, err = readGrid(r, w, cfg.f)
+	case "paste":
+		g, err = pasteGrid(r, w, cfg.f)
+	case "text":
+		g, err = parseText(r, cfg.f)
+	case "compact":
+		g, err = parseCompact(r, cfg.f)
+	case "json":
+		g, err = parseJSON(r, cfg.f)
+	case "triangles":
+		g, err = parseTriangles(r)
+	case "hex":
+		g, err = parseHex(r, cfg.f)
+	case "image":
+		var b []byte
+		if b, err = ioutil.ReadAll(r); err == nil {
+			g, err = parseImage(bytes.NewReader(b), maxImageCells)
+		}
+	}
+	if err != nil && err != errorUserTerminated {
+		return nil, inputError{err}
+	}
+	return g, err
+}
+
+// maxImageCells bounds the images read from the command line.
+const maxImageCells = 1 << 28
+
+// readFile reads a grid from the named file, or standard input for -.
+func (c cli) readFile(cfg *config, name string) ([][]int, error) {
+	in, err := c.open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	return cfg.readGrid(in, c.stdout)
+}
+
+// search reads the input file and searches it. An interrupt stops the search and returns the shapes found so far
+// along with context.Canceled.
+func (c cli) search(cfg *config) (searchResult, error) {
+	opts := cfg.opts
+	if cfg.progress {
+		opts.Progress = func(p search.Progress) {
+			if p.Rows == 0 {
+				fmt.Fprintf(c.stderr, "\rscanned %d rows, %d shapes found", p.RowsScanned, p.Components)
+				return
+			}
+			fmt.Fprintf(c.stderr, "\rscanned %d of %d rows, %d shapes found", p.RowsScanned, p.Rows, p.Components)
+			if p.RowsScanned == p.Rows {
+				fmt.Fprintln(c.stderr)
+			}
+		}
+	}
+	in, err := c.open(cfg.in)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+
+	ctx, cancel := interruptible()
+	defer cancel()
+
+	switch cfg.format {
+	case "stream":
+		rows := newTextRows(in, cfg.f)
+		s, err := search.NewStream(ctx, rows, opts)
+		if rows.err != nil {
+			return nil, inputError{rows.err}
+		}
+		return c.searched(s, s != nil, err)
+	case "points":
+		cells, err := parsePoints(in)
+		if err != nil {
+			return nil, inputError{err}
+		}
+		s, err := search.NewUnbounded(ctx, cells, opts)
+		return c.searched(s, s != nil, err)
+	}
+	g, err := cfg.readGrid(in, c.stdout)
+	if err != nil {
+		return nil, err
+	}
+	s, err := search.NewWithContext(ctx, g, opts)
+	return c.searched(s, s != nil, err)
+}
+
+// interruptible returns a context that is cancelled when the user interrupts the program, until cancel is called.
+func interruptible() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		select {
+		case <-interrupt:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(interrupt)
+		cancel()
+	}
+}
+
+// searched returns what a search found. Ok reports whether the search returned anything, since a nil search
+// passed in is no longer nil as a searchResult.
+func (c cli) searched(s searchResult, ok bool, err error) (searchResult, error) {
+	if err = c.finished(ok, err); err != nil && err != context.Canceled {
+		return nil, err
+	}
+	return s, err
+}
+
+// finished returns the error a search that returned err ends with, ok reporting whether it returned anything. An
+// interrupted search keeps the shapes found so far and ends with context.Canceled, and one that returned nothing
+// fails as a cancelled prompt does, so commands never write out a search that is not there.
+func (c cli) finished(ok bool, err error) error {
+	if err == context.Canceled {
+		if !ok {
+			return errorUserTerminated
+		}
+		fmt.Fprintln(c.stderr, "search interrupted, showing the shapes found so far")
+	}
+	return err
+}
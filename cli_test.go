@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/murphybytes/shapes/search"
+)
+
+// runCLI runs the program with the given arguments and standard input.
+func runCLI(args []string, stdin string) (code int, stdout, stderr string) {
+	var out, errOut bytes.Buffer
+	code = cli{stdin: strings.NewReader(stdin), stdout: &out, stderr: &errOut}.run(args)
+	return code, out.String(), errOut.String()
+}
+
+func TestRun(t *testing.T) {
+	tt := []struct {
+		args       []string
+		stdin      string
+		wantCode   int
+		wantStdout string
+		wantStderr string
+	}{
+		{args: []string{"help"}, wantCode: exitOK, wantStdout: "commands:"},
+		{args: []string{"--help"}, wantCode: exitOK, wantStdout: "commands:"},
+		{args: []string{"help", "gen"}, wantCode: exitOK, wantStdout: "usage: shapes gen [flags]\n"},
+		{args: []string{"count", "-h"}, wantCode: exitOK, wantStdout: "-equivalence"},
+		{args: []string{"bogus"}, wantCode: exitUsage, wantStderr: "unknown command"},
+		{args: []string{"find", "-bogus"}, wantCode: exitUsage, wantStderr: "flag provided but not defined"},
+		{args: []string{"find", "-topology", "sphere"}, wantCode: exitUsage, wantStderr: "unknown option value"},
+		{args: []string{"find", "-out", "pdf"}, wantCode: exitUsage, wantStderr: `-out "pdf" is not one of`},
+		{args: []string{"find", "extra"}, wantCode: exitUsage, wantStderr: "wrong number of arguments"},
+		{args: []string{"find", "-format", "text"}, stdin: "1 0\n1\n", wantCode: exitParse, wantStderr: "rows in grid differ in length"},
+		{args: []string{"find", "-format", "json"}, stdin: "{", wantCode: exitParse},
+		{args: []string{"find", "-in", "/no/such/file"}, wantCode: exitFailure},
+		{args: []string{"edit", "/no/such/grid.txt"}, wantCode: exitFailure, wantStderr: "not a terminal"},
+		// cancelled at the prompt after the dimensions
+		{args: nil, stdin: "1 1\nX\n", wantCode: exitCancelled, wantStderr: "user terminated"},
+		// the program reads the grid from the prompts when no command is given
+		{args: []string{"-across"}, stdin: "1 2\nC\n1 0\nC\n", wantCode: exitOK, wantStdout: "    X\n-----\n"},
+		{args: []string{"find", "-format", "text"}, stdin: "1 1\n0 0\n", wantCode: exitOK, wantStdout: "    XX\n------\n"},
+		{args: []string{"find", "-format", "paste"}, stdin: "1 1\n0 0\n\nC\n", wantCode: exitOK, wantStdout: "?     XX\n------\n"},
+		{args: []string{"find", "-lattice", "triangle"}, stdin: "^v..\n....\n", wantCode: exitOK, wantStdout: "^v"},
+		{args: []string{"find", "-lattice", "hex", "-format", "hex"}, stdin: "1 1 0 0\n 0 1 0 0\n", wantCode: exitOK, wantStdout: "    X X \n       X\n"},
+		{args: []string{"find", "-lattice", "hex", "-format", "hex"}, stdin: "1 1\n0 1\n", wantCode: exitParse, wantStderr: "odd rows"},
+		{args: []string{"find", "-format", "volume"}, stdin: "1 0\n\n1 0\n", wantCode: exitOK, wantStdout: "    X\n\n    X\n"},
+		{args: []string{"count", "-format", "stream"}, stdin: "// a comment\n1 0\n0 1\n", wantCode: exitOK, wantStdout: "1 unique shapes in 2"},
+		{args: []string{"count", "-format", "stream"}, stdin: "1 0\n0 2\n", wantCode: exitParse, wantStderr: "one or zero"},
+		{args: []string{"count", "-format", "stream", "-labels"}, stdin: "1 0\n0 2\n", wantCode: exitOK, wantStdout: "2 unique shapes in 2"},
+		// a stream that reads well but cannot be searched is not an input error
+		{args: []string{"count", "-format", "stream", "-morph", "open"}, stdin: "1 0\n", wantCode: exitFailure},
+		{args: []string{"find", "-format", "volume", "-morph", "open"}, stdin: "1 0\n", wantCode: exitUsage, wantStderr: "do not apply to volume input"},
+	}
+	for i, tc := range tt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			code, stdout, stderr := runCLI(tc.args, tc.stdin)
+			if code != tc.wantCode {
+				t.Fatalf("want exit code %d got %d, stderr %q", tc.wantCode, code, stderr)
+			}
+			if !strings.Contains(stdout, tc.wantStdout) {
+				t.Fatalf("want standard output to contain %q got %q", tc.wantStdout, stdout)
+			}
+			if !strings.Contains(stderr, tc.wantStderr) {
+				t.Fatalf("want standard error to contain %q got %q", tc.wantStderr, stderr)
+			}
+		})
+	}
+}
+
+func TestSearched(t *testing.T) {
+	c := cli{stderr: &bytes.Buffer{}}
+	// interrupted before anything was found, there is nothing to write out
+	if s, err := c.searched(nil, false, context.Canceled); s != nil || err != errorUserTerminated {
+		t.Fatalf("want no result and error %v got %v, %v", errorUserTerminated, s, err)
+	}
+	found, err := search.New([][]int{{1}})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if s, err := c.searched(found, true, context.Canceled); s == nil || err != context.Canceled {
+		t.Fatalf("want the shapes found so far and error %v got %v, %v", context.Canceled, s, err)
+	}
+}
+
+func TestInterruptible(t *testing.T) {
+	ctx, cancel := interruptible()
+	defer cancel()
+	p, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if err := p.Signal(os.Interrupt); err != nil {
+		t.Skip("interrupts cannot be sent here:", err)
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("want the context cancelled by the interrupt")
+	}
+}
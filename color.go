@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/murphybytes/shapes/search"
+)
+
+// colorMode is how the shapes of a grid are colored when it is written to a terminal.
+type colorMode int
+
+const (
+	noColor colorMode = iota
+	// color256 draws shapes in turn in the colors of palette.
+	color256
+	// trueColor gives every shape a hue of its own.
+	trueColor
+)
+
+// colorModes are the values of the -color flag.
+var colorModes = []string{"auto", "never", "256", "truecolor"}
+
+// colorFor returns the mode that a -color flag asks for when writing to w. Auto colors only a terminal, in true
+// color when the terminal says through COLORTERM that it has it.
+func colorFor(flag string, w io.Writer) colorMode {
+	switch flag {
+	case "never":
+		return noColor
+	case "256":
+		return color256
+	case "truecolor":
+		return trueColor
+	}
+	f, ok := w.(*os.File)
+	if !ok || !isTerminal(int(f.Fd())) {
+		return noColor
+	}
+	if ct := os.Getenv("COLORTERM"); ct == "truecolor" || ct == "24bit" {
+		return trueColor
+	}
+	return color256
+}
+
+// palette holds the 256 color codes that unique shapes are drawn in, in turn: the strongest colors first, then
+// every other color of the six by six by six cube that black text reads on.
+var palette = cubePalette([]int{196, 46, 33, 226, 201, 51, 208, 129, 118, 39, 214, 165, 82, 27, 220, 99})
+
+// cubePalette returns the colors first followed by the rest of the color cube light enough for black text. The rest
+// are taken a fixed stride apart, so colors next to each other in the cube are not drawn one after another.
+func cubePalette(first []int) []int {
+	in := make(map[int]bool, len(first))
+	for _, c := range first {
+		in[c] = true
+	}
+	// level is the intensity of each of the six steps of the cube along an axis.
+	level := func(l int) int {
+		if l == 0 {
+			return 0
+		}
+		return 55 + 40*l
+	}
+	var rest []int
+	for c := 16; c < 232; c++ {
+		r, g, b := level((c-16)/36), level((c-16)/6%6), level((c-16)%6)
+		if !in[c] && 299*r+587*g+114*b >= 128*1000 {
+			rest = append(rest, c)
+		}
+	}
+	stride := len(rest) * 5 / 8
+	for gcd(stride, len(rest)) != 1 {
+		stride++
+	}
+	p := append([]int(nil), first...)
+	for i := range rest {
+		p = append(p, rest[i*stride%len(rest)])
+	}
+	return p
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// shade returns the escape sequence that draws the cells of the unique shape with index i, black on its color.
+func (m colorMode) shade(i int) string {
+	switch m {
+	case color256:
+		return fmt.Sprintf("\x1b[38;5;16;48;5;%dm", palette[i%len(palette)])
+	case trueColor:
+		r, g, b := hue(360 * spread(i))
+		return fmt.Sprintf("\x1b[38;2;0;0;0;48;2;%d;%d;%dm", r, g, b)
+	}
+	return ""
+}
+
+// spread returns a fraction from 0 to 1 for each index, reversing its binary digits after the point, so that the
+// first n indexes are spread evenly over the range for any power of two n and no two indexes share a fraction.
+func spread(i int) float64 {
+	f, step := 0.0, 0.5
+	for ; i > 0; i >>= 1 {
+		if i&1 == 1 {
+			f += step
+		}
+		step /= 2
+	}
+	return f
+}
+
+// hue returns a light color of the hue h degrees round the color wheel.
+func hue(h float64) (r, g, b int) {
+	h = math.Mod(h, 360) / 60
+	x := 1 - math.Abs(math.Mod(h, 2)-1)
+	var c [3]float64
+	switch int(h) {
+	case 0:
+		c = [3]float64{1, x, 0}
+	case 1:
+		c = [3]float64{x, 1, 0}
+	case 2:
+		c = [3]float64{0, 1, x}
+	case 3:
+		c = [3]float64{0, x, 1}
+	case 4:
+		c = [3]float64{x, 0, 1}
+	default:
+		c = [3]float64{1, 0, x}
+	}
+	v := func(f float64) int { return int(math.Round(255 * (0.3 + 0.7*f))) }
+	return v(c[0]), v(c[1]), v(c[2])
+}
+
+// writeColor writes a grid as text the way writeGrid does, with the cells of each shape colored by its unique shape
+// in labels, and then a legend of the colors with each unique shape and the number of times it occurs.
+func writeColor(w io.Writer, g, labels [][]int, r search.Result, m colorMode) error {
+	for y, row := range g {
+		for x, v := range row {
+			sep := " "
+			if x == len(row)-1 {
+				sep = "\n"
+			}
+			if labels != nil && labels[y][x] > 0 {
+				if _, err := fmt.Fprintf(w, "%s%d%s%s", m.shade(labels[y][x]-1), v, reset, sep); err != nil {
+					return err
+				}
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "%d%s", v, sep); err != nil {
+				return err
+			}
+		}
+	}
+	fmt.Fprintln(w)
+	for i, shp := range r.Shapes {
+		fmt.Fprintf(w, "%s  %s shape %d, value %d, %d cells, occurs %d times\n", m.shade(i), reset, i+1, shp.Value,
+			len(shp.Cells), shp.Count)
+	}
+	_, err := fmt.Fprintf(w, "%d unique shapes in %d\n", len(r.Shapes), r.Components)
+	return err
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+
+	"github.com/murphybytes/shapes/search"
+)
+
+func TestColorFor(t *testing.T) {
+	tt := []struct {
+		flag string
+		want colorMode
+	}{
+		{flag: "never", want: noColor},
+		{flag: "256", want: color256},
+		{flag: "truecolor", want: trueColor},
+		// a buffer is not a terminal
+		{flag: "auto", want: noColor},
+	}
+	for i, tc := range tt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			if got := colorFor(tc.flag, &bytes.Buffer{}); got != tc.want {
+				t.Fatalf("want %d got %d", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestShade(t *testing.T) {
+	tt := []struct {
+		mode colorMode
+		i    int
+		want string
+	}{
+		{mode: noColor, i: 0, want: ""},
+		{mode: color256, i: 0, want: "\x1b[38;5;16;48;5;196m"},
+		{mode: color256, i: len(palette) + 1, want: "\x1b[38;5;16;48;5;46m"},
+		{mode: trueColor, i: 0, want: "\x1b[38;2;0;0;0;48;2;255;77;77m"},
+		{mode: trueColor, i: 1, want: "\x1b[38;2;0;0;0;48;2;77;255;255m"},
+	}
+	for i, tc := range tt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			if got := tc.mode.shade(tc.i); got != tc.want {
+				t.Fatalf("want %q got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestShadesDistinct(t *testing.T) {
+	tt := []struct {
+		mode colorMode
+		n    int
+	}{
+		{mode: color256, n: len(palette)},
+		{mode: trueColor, n: 512},
+	}
+	for i, tc := range tt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			if tc.n <= 16 {
+				t.Fatalf("want more than 16 colors got %d", tc.n)
+			}
+			seen := make(map[string]int)
+			for j := 0; j < tc.n; j++ {
+				shade := tc.mode.shade(j)
+				if k, ok := seen[shade]; ok {
+					t.Fatalf("shapes %d and %d share %q", k, j, shade)
+				}
+				seen[shade] = j
+			}
+		})
+	}
+}
+
+func TestWriteColor(t *testing.T) {
+	g := [][]int{{1, 0, 2}, {1, 0, 0}}
+	s, err := search.NewWithOptions(g, search.Options{Topology: search.Plane})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	var b bytes.Buffer
+	if err := writeColor(&b, g, s.Labels(), s.Result(), color256); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	red, green := color256.shade(0), color256.shade(1)
+	want := red + "1" + reset + " 0 " + green + "2" + reset + "\n" +
+		red + "1" + reset + " 0 0\n" +
+		"\n" +
+		red + "  " + reset + " shape 1, value 1, 2 cells, occurs 1 times\n" +
+		green + "  " + reset + " shape 2, value 2, 1 cells, occurs 1 times\n" +
+		"2 unique shapes in 2\n"
+	if b.String() != want {
+		t.Fatalf("want\n%q\ngot\n%q", want, b.String())
+	}
+}
@@ -0,0 +1,443 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/murphybytes/shapes/search"
+)
+
+const errorNoShape = errorType("no pattern given, use -shape")
+const errorGenSize = errorType("-rows and -cols must be positive")
+const errorDensity = errorType("-density must be from 0 to 1")
+const errorValues = errorType("-values must be positive")
+const errorVolumeFlags = errorType("-morph, -filter, -parallel and -lattice do not apply to volume input")
+
+// check fills in the input format a triangle lattice is drawn in when none was given and checks the format is
+// one the command reads.
+func (cfg *config) check(fs *flag.FlagSet, formats []string) error {
+	given := false
+	fs.Visit(func(f *flag.Flag) { given = given || f.Name == "format" })
+	if !given && cfg.opts.Lattice == search.Triangle {
+		cfg.format = "triangles"
+	}
+	return oneOf("format", cfg.format, formats...)
+}
+
+func (c cli) find(fs *flag.FlagSet, args []string) error {
+	formats := append(append([]string(nil), gridFormats...), "points", "stream", "volume")
+	cfg := searchFlags(fs, true, formats...)
+	out := fs.String("out", "text", "output format, one of text, json, contours, geojson, wkt, svg, or text, obj and stl for a volume")
+	affine := fs.String("affine", "0,1,0,0,0,1", "transform from column and row to x and y for geojson and wkt output, in GDAL geotransform order")
+	if err := c.parse(fs, args, 0); err != nil {
+		return err
+	}
+	if err := cfg.check(fs, formats); err != nil {
+		return err
+	}
+	a, err := parseAffine(*affine)
+	if err != nil {
+		return usageError{err}
+	}
+	if cfg.format == "volume" {
+		if err := oneOf("out", *out, "text", "obj", "stl"); err != nil {
+			return err
+		}
+		var grid bool
+		fs.Visit(func(f *flag.Flag) {
+			grid = grid || f.Name == "morph" || f.Name == "filter" || f.Name == "parallel" || f.Name == "lattice"
+		})
+		if grid {
+			return usageError{errorVolumeFlags}
+		}
+		return c.findVolume(cfg, *out)
+	}
+	if err := oneOf("out", *out, "text", "json", "contours", "geojson", "wkt", "svg"); err != nil {
+		return err
+	}
+
+	s, err := c.search(cfg)
+	if err != nil && err != context.Canceled {
+		return err
+	}
+	var werr error
+	switch *out {
+	case "json":
+		werr = json.NewEncoder(c.stdout).Encode(s.Result())
+	case "contours":
+		s.PrintContours(c.stdout)
+	case "geojson":
+		werr = s.WriteGeoJSON(c.stdout, a)
+	case "wkt":
+		werr = s.WriteWKT(c.stdout, a)
+	case "svg":
+		werr = s.WriteSVG(c.stdout)
+	default:
+		s.Print(c.stdout)
+	}
+	if werr != nil {
+		return werr
+	}
+	return err
+}
+
+// findVolume finds the unique shapes in a volume and writes them out. An interrupt stops the search and writes out
+// the shapes found so far.
+func (c cli) findVolume(cfg *config, out string) error {
+	in, err := c.open(cfg.in)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	v, err := parseVolume(in, cfg.f)
+	if err != nil {
+		return inputError{err}
+	}
+	opts := search.VolumeOptions{AcrossValues: cfg.opts.AcrossValues, Equivalence: cfg.opts.Equivalence, Glyphs: cfg.opts.Glyphs}
+	if cfg.opts.Connectivity == search.EightWay {
+		opts.Connectivity = search.TwentySixWay
+	}
+	if cfg.opts.Topology == search.Plane {
+		opts.Plane = [3]bool{true, true, true}
+	}
+	ctx, cancel := interruptible()
+	defer cancel()
+	s, err := search.NewVolume(ctx, v, opts)
+	if err = c.finished(s != nil, err); err != nil && err != context.Canceled {
+		return err
+	}
+	var werr error
+	switch out {
+	case "obj":
+		werr = s.WriteOBJ(c.stdout)
+	case "stl":
+		werr = s.WriteSTL(c.stdout)
+	default:
+		s.Print(c.stdout)
+	}
+	if werr != nil {
+		return werr
+	}
+	return err
+}
+
+// counts is the output of the count command as JSON.
+type counts struct {
+	Components int   `json:"components"`
+	Unique     int   `json:"unique"`
+	Counts     []int `json:"counts"`
+	Partial    bool  `json:"partial,omitempty"`
+}
+
+func (c cli) count(fs *flag.FlagSet, args []string) error {
+	formats := append(append([]string(nil), gridFormats...), "points", "stream")
+	cfg := searchFlags(fs, true, formats...)
+	out := fs.String("out", "text", "output format, text or json")
+	if err := c.parse(fs, args, 0); err != nil {
+		return err
+	}
+	if err := cfg.check(fs, formats); err != nil {
+		return err
+	}
+	if err := oneOf("out", *out, "text", "json"); err != nil {
+		return err
+	}
+
+	s, err := c.search(cfg)
+	if err != nil && err != context.Canceled {
+		return err
+	}
+	r := s.Result()
+	if *out == "json" {
+		n := counts{Components: r.Components, Unique: len(r.Shapes), Counts: []int{}, Partial: r.Partial}
+		for _, shp := range r.Shapes {
+			n.Counts = append(n.Counts, shp.Count)
+		}
+		if werr := json.NewEncoder(c.stdout).Encode(n); werr != nil {
+			return werr
+		}
+		return err
+	}
+	tw := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "shape\tvalue\tcells\tcount")
+	for i, shp := range r.Shapes {
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%d\n", i+1, shp.Value, len(shp.Cells), shp.Count)
+	}
+	fmt.Fprintf(tw, "%d unique shapes in %d\n", len(r.Shapes), r.Components)
+	if werr := tw.Flush(); werr != nil {
+		return werr
+	}
+	return err
+}
+
+// gridOutputs are the formats a grid is written out in.
+var gridOutputs = []string{"text", "compact", "json", "points"}
+
+// writeGrid writes a grid as text that parseText reads, as compact text in the format's characters that
+// parseCompact reads, as JSON that parseJSON reads, or as the column and row of each cell that is not empty, which
+// parsePoints reads.
+func writeGrid(w io.Writer, g [][]int, out string, f format) error {
+	switch out {
+	case "compact":
+		set, unset := f.glyphs()
+		for _, row := range g {
+			line := make([]rune, len(row))
+			for x, v := range row {
+				switch {
+				case v == 0:
+					line[x] = []rune(unset)[0]
+				case v == 1:
+					line[x] = []rune(set)[0]
+				case v <= 9:
+					line[x] = rune('0' + v)
+				default:
+					return errorCompactValue
+				}
+			}
+			if _, err := fmt.Fprintln(w, string(line)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "json":
+		return json.NewEncoder(w).Encode(struct {
+			Grid [][]int `json:"grid"`
+		}{g})
+	case "points":
+		for y, row := range g {
+			for x, v := range row {
+				if v != 0 {
+					if _, err := fmt.Fprintf(w, "%d,%d\n", x, y); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		return nil
+	}
+	for _, row := range g {
+		for x, v := range row {
+			sep := " "
+			if x == len(row)-1 {
+				sep = "\n"
+			}
+			if _, err := fmt.Fprintf(w, "%d%s", v, sep); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c cli) render(fs *flag.FlagSet, args []string) error {
+	cfg := searchFlags(fs, true, gridFormats...)
+	out := fs.String("out", "text", "output format, one of text, compact, json or points")
+	color := fs.String("color", "auto", "color the shapes of text output by unique shape and add a legend, one of auto, for a terminal only, never, 256 or truecolor")
+	if err := c.parse(fs, args, 0); err != nil {
+		return err
+	}
+	if err := cfg.check(fs, gridFormats); err != nil {
+		return err
+	}
+	if err := oneOf("out", *out, gridOutputs...); err != nil {
+		return err
+	}
+	if err := oneOf("color", *color, colorModes...); err != nil {
+		return err
+	}
+	g, err := c.readFile(cfg, cfg.in)
+	if err != nil {
+		return err
+	}
+	// The grid written is the one searched, after any preprocessing.
+	if len(cfg.opts.Preprocess) > 0 {
+		if g, err = cfg.opts.Preprocess.Apply(context.Background(), g, cfg.opts); err != nil {
+			return err
+		}
+		cfg.opts.Preprocess = nil
+	}
+	m := colorFor(*color, c.stdout)
+	if *out != "text" || m == noColor {
+		return writeGrid(c.stdout, g, *out, cfg.f)
+	}
+	s, err := search.NewWithOptions(g, cfg.opts)
+	if err != nil {
+		return err
+	}
+	return writeColor(c.stdout, g, s.Labels(), s.Result(), m)
+}
+
+func (c cli) gen(fs *flag.FlagSet, args []string) error {
+	rows := fs.Int("rows", 10, "number of rows")
+	cols := fs.Int("cols", 10, "number of columns")
+	density := fs.Float64("density", 0.3, "chance that a cell is set, from 0 to 1")
+	values := fs.Int("values", 1, "number of labels, set cells are given one from 1 up to it at random")
+	seed := fs.Int64("seed", 0, "seed for the random grid, 0 for a different grid each run")
+	out := fs.String("out", "text", "output format, one of text, compact, json or points")
+	var f format
+	compactFlags(fs, &f)
+	if err := c.parse(fs, args, 0); err != nil {
+		return err
+	}
+	if err := oneOf("out", *out, gridOutputs...); err != nil {
+		return err
+	}
+	switch {
+	case *rows <= 0 || *cols <= 0:
+		return usageError{errorGenSize}
+	case *density < 0 || *density > 1:
+		return usageError{errorDensity}
+	case *values <= 0:
+		return usageError{errorValues}
+	}
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	r := rand.New(rand.NewSource(*seed))
+	g := make([][]int, *rows)
+	for y := range g {
+		g[y] = make([]int, *cols)
+		for x := range g[y] {
+			if r.Float64() < *density {
+				g[y][x] = 1 + r.Intn(*values)
+			}
+		}
+	}
+	return writeGrid(c.stdout, g, *out, f)
+}
+
+// occurrences returns how many times each unique shape of the pattern occurs among the shapes of s.
+func occurrences(s, pattern searchResult) []int {
+	found := make(map[string]int)
+	r := s.Result()
+	for i, k := range s.Keys() {
+		found[k] = r.Shapes[i].Count
+	}
+	var n []int
+	for _, k := range pattern.Keys() {
+		n = append(n, found[k])
+	}
+	return n
+}
+
+func (c cli) match(fs *flag.FlagSet, args []string) error {
+	formats := append(append([]string(nil), gridFormats...), "points", "stream")
+	cfg := searchFlags(fs, true, formats...)
+	shape := fs.String("shape", "", "text file holding the pattern, searched on a plane with the other options given, besides -filter and -morph")
+	out := fs.String("out", "text", "output format, text or json")
+	if err := c.parse(fs, args, 0); err != nil {
+		return err
+	}
+	if err := cfg.check(fs, formats); err != nil {
+		return err
+	}
+	if err := oneOf("out", *out, "text", "json"); err != nil {
+		return err
+	}
+	if *shape == "" {
+		return usageError{errorNoShape}
+	}
+	pcfg := *cfg
+	pcfg.format = "text"
+	pg, err := c.readFile(&pcfg, *shape)
+	if err != nil {
+		return err
+	}
+	// The filter and morph steps are for the grid; the pattern is taken as it is drawn.
+	popts := cfg.opts
+	popts.Topology, popts.Parallel = search.Plane, false
+	popts.Filter, popts.Preprocess = nil, nil
+	p, err := search.NewWithOptions(pg, popts)
+	if err != nil {
+		return err
+	}
+
+	s, err := c.search(cfg)
+	if err != nil && err != context.Canceled {
+		return err
+	}
+	n := occurrences(s, p)
+	if *out == "json" {
+		if werr := json.NewEncoder(c.stdout).Encode(struct {
+			Counts []int `json:"counts"`
+		}{n}); werr != nil {
+			return werr
+		}
+		return err
+	}
+	for i, count := range n {
+		fmt.Fprintf(c.stdout, "pattern shape %d occurs %d times\n", i+1, count)
+	}
+	return err
+}
+
+func (c cli) diff(fs *flag.FlagSet, args []string) error {
+	formats := []string{"text", "compact", "json", "image", "triangles", "hex"}
+	cfg := searchFlags(fs, false, formats...)
+	out := fs.String("out", "text", "output format, text or json")
+	if err := c.parse(fs, args, 2); err != nil {
+		return err
+	}
+	if err := cfg.check(fs, formats); err != nil {
+		return err
+	}
+	if err := oneOf("out", *out, "text", "json"); err != nil {
+		return err
+	}
+	var grids [2][][]int
+	for i := range grids {
+		var err error
+		if grids[i], err = c.readFile(cfg, fs.Arg(i)); err != nil {
+			return err
+		}
+	}
+	d, err := search.Compare(context.Background(), grids[0], grids[1], cfg.opts)
+	if err != nil {
+		return err
+	}
+	if *out == "json" {
+		return json.NewEncoder(c.stdout).Encode(d)
+	}
+	d.Print(c.stdout)
+	return nil
+}
+
+func (c cli) edit(fs *flag.FlagSet, args []string) error {
+	var opts search.Options
+	optionFlags(fs, &opts)
+	rows := fs.Int("rows", 10, "number of rows of a new grid")
+	cols := fs.Int("cols", 20, "number of columns of a new grid")
+	if err := c.parse(fs, args, 1); err != nil {
+		return err
+	}
+	if *rows <= 0 || *cols <= 0 {
+		return usageError{errorGenSize}
+	}
+	e, err := newEditor(fs.Arg(0), *rows, *cols, opts)
+	if err != nil {
+		return err
+	}
+	in, ok := c.stdin.(*os.File)
+	if !ok || !isTerminal(int(in.Fd())) {
+		return errorNoTerminal
+	}
+	restore, err := makeRaw(int(in.Fd()))
+	if err != nil {
+		return err
+	}
+	defer restore()
+	// Leave room for the status and help lines, at two columns a cell.
+	if h, w, err := terminalSize(int(in.Fd())); err == nil && h > 3 && w > 1 {
+		e.view = [2]int{h - 3, w / 2}
+	}
+	fmt.Fprint(c.stdout, altScreen+hideCursor)
+	defer fmt.Fprint(c.stdout, showCursor+mainScreen)
+	return e.run(c.stdin, c.stdout)
+}
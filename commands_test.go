@@ -0,0 +1,119 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestCommands(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shapes")
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"old.txt":     "1 1 0 0\n0 0 0 0\n1 0 0 1\n1 0 0 1\n",
+		"new.txt":     "1 1 0 1\n0 0 0 0\n1 1 0 1\n0 0 0 0\n",
+		"pattern.txt": "1\n1\n",
+		"single.txt":  "1\n",
+	}
+	for name, body := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(body), 0600); err != nil {
+			t.Fatal("unexpected error", err)
+		}
+	}
+	oldFile, newFile, pattern := filepath.Join(dir, "old.txt"), filepath.Join(dir, "new.txt"), filepath.Join(dir, "pattern.txt")
+	single := filepath.Join(dir, "single.txt")
+
+	tt := []struct {
+		args     []string
+		stdin    string
+		wantCode int
+		want     string
+	}{
+		{
+			args: []string{"count", "-format", "text", "-topology", "plane", "-in", oldFile},
+			want: "shape  value  cells  count\n1      1      2      1\n2      1      2      2\n2 unique shapes in 3\n",
+		},
+		{
+			args: []string{"count", "-format", "text", "-topology", "plane", "-equivalence", "rotation", "-out", "json", "-in", oldFile},
+			want: `{"components":3,"unique":1,"counts":[3]}` + "\n",
+		},
+		{args: []string{"render", "-format", "text", "-out", "points"}, stdin: "0 1\n1 0\n", want: "1,0\n0,1\n"},
+		{args: []string{"render", "-format", "json"}, stdin: `{"grid":[[0,1],[1,0]]}`, want: "0 1\n1 0\n"},
+		// text written anywhere but a terminal is left plain
+		{args: []string{"render", "-format", "text"}, stdin: "0 1\n1 0\n", want: "0 1\n1 0\n"},
+		{
+			args:  []string{"render", "-format", "text", "-topology", "plane", "-color", "256"},
+			stdin: "0 1\n1 0\n",
+			want: "0 \x1b[38;5;16;48;5;196m1\x1b[0m\n\x1b[38;5;16;48;5;196m1\x1b[0m 0\n\n" +
+				"\x1b[38;5;16;48;5;196m  \x1b[0m shape 1, value 1, 1 cells, occurs 2 times\n1 unique shapes in 2\n",
+		},
+		{args: []string{"render", "-color", "16"}, wantCode: exitUsage},
+		{args: []string{"render", "-format", "compact", "-out", "compact", "-morph", "min=2"}, stdin: "#..\n..#\n.##\n", want: "...\n..#\n.##\n"},
+		{args: []string{"render", "-morph", "smooth"}, wantCode: exitUsage},
+		{
+			args:  []string{"count", "-format", "compact", "-topology", "plane", "-filter", "cells>=2,!edge"},
+			stdin: "#....\n..##.\n.....\n.##..\n....#\n",
+			want:  "shape  value  cells  count\n1      1      2      2\n1 unique shapes in 2\n",
+		},
+		{args: []string{"count", "-filter", "cells>>2"}, wantCode: exitUsage},
+		{args: []string{"count", "-format", "points", "-filter", "edge"}, stdin: "0 0\n", wantCode: exitFailure},
+		{
+			args:  []string{"count", "-format", "compact", "-topology", "plane", "-morph", "open=box"},
+			stdin: "#....\n.###.\n.###.\n.###.\n....#\n",
+			want:  "shape  value  cells  count\n1      1      9      1\n1 unique shapes in 1\n",
+		},
+		{args: []string{"render", "-format", "compact", "-out", "compact"}, stdin: "// a glider\n.#.\n..#\n###\n", want: ".#.\n..#\n###\n"},
+		{args: []string{"render", "-format", "compact", "-set", "@", "-unset", "-", "-labels"}, stdin: "-@2\n", want: "0 1 2\n"},
+		{args: []string{"render", "-format", "text", "-labels", "-out", "compact"}, stdin: "0 1 12\n", wantCode: exitFailure},
+		{args: []string{"find", "-format", "compact", "-topology", "plane", "-glyphs", "#."}, stdin: "#.\n##\n", want: "    #.\n    ##\n------\n"},
+		{args: []string{"find", "-glyphs", "#"}, wantCode: exitUsage},
+		{args: []string{"gen", "-rows", "2", "-cols", "3", "-density", "1", "-values", "1"}, want: "1 1 1\n1 1 1\n"},
+		{args: []string{"gen", "-rows", "1", "-cols", "3", "-density", "1", "-out", "compact", "-set", "o"}, want: "ooo\n"},
+		{args: []string{"gen", "-rows", "0"}, wantCode: exitUsage},
+		{args: []string{"gen", "-density", "2"}, wantCode: exitUsage},
+		{
+			args: []string{"match", "-format", "text", "-topology", "plane", "-in", oldFile, "-shape", pattern},
+			want: "pattern shape 1 occurs 2 times\n",
+		},
+		{
+			args: []string{"match", "-format", "text", "-topology", "plane", "-equivalence", "rotation", "-in", oldFile, "-shape", pattern, "-out", "json"},
+			want: `{"counts":[3]}` + "\n",
+		},
+		{args: []string{"match", "-format", "text", "-in", oldFile}, wantCode: exitUsage},
+		{
+			// the pattern touches its own edge, but only the shapes of the grid are filtered
+			args:  []string{"match", "-format", "compact", "-topology", "plane", "-filter", "!edge", "-shape", single},
+			stdin: "#...\n..#.\n....\n",
+			want:  "pattern shape 1 occurs 1 times\n",
+		},
+		{
+			// the upright dominoes of the old grid are gone, and a second domino across and two single cells appeared
+			args: []string{"diff", "-topology", "plane", oldFile, newFile},
+			want: "- shape 2 of old, 2 times\n    X\n    X\n-----\n" +
+				"~ shape 1 of new, 2 times, was 1\n    XX\n------\n" +
+				"+ shape 2 of new, 2 times\n    X\n-----\n" +
+				"\n2 cells set, 2 cleared, 0 relabeled\n    ##.+\n    ....\n    #+.#\n    -..-\n",
+		},
+		{
+			args: []string{"diff", "-topology", "plane", "-out", "json", oldFile, oldFile},
+			want: `{"removed":[],"added":[],"changed":[],"rows":4,"cols":4,"cells":[]}` + "\n",
+		},
+		{args: []string{"diff", oldFile}, wantCode: exitUsage},
+	}
+	for i, tc := range tt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			code, stdout, stderr := runCLI(tc.args, tc.stdin)
+			if code != tc.wantCode {
+				t.Fatalf("want exit code %d got %d, stderr %q", tc.wantCode, code, stderr)
+			}
+			if tc.want != "" && stdout != tc.want {
+				t.Fatalf("want\n%q\ngot\n%q", tc.want, stdout)
+			}
+		})
+	}
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/murphybytes/shapes/search"
+)
+
+// Keys that are not a single printable character are given negative codes.
+const (
+	keyUp rune = -1 - iota
+	keyDown
+	keyLeft
+	keyRight
+	keyUnknown
+)
+
+const (
+	keyCtrlC = 0x03
+	keyCtrlR = 0x12
+	keyEsc   = 0x1b
+)
+
+// readKey reads one key press from a terminal in raw mode, turning the escape sequences of the arrow keys into
+// their key codes.
+func readKey(r *bufio.Reader) (rune, error) {
+	c, _, err := r.ReadRune()
+	if err != nil || c != keyEsc {
+		return c, err
+	}
+	// A lone escape arrives on its own; the rest of a sequence follows in the same read.
+	if r.Buffered() == 0 {
+		return keyEsc, nil
+	}
+	if b, _ := r.ReadByte(); b != '[' && b != 'O' {
+		return keyUnknown, nil
+	}
+	b, err := r.ReadByte()
+	if err != nil {
+		return keyUnknown, err
+	}
+	switch b {
+	case 'A':
+		return keyUp, nil
+	case 'B':
+		return keyDown, nil
+	case 'C':
+		return keyRight, nil
+	case 'D':
+		return keyLeft, nil
+	}
+	// Skip the parameters of any other sequence up to its final byte.
+	for b < 0x40 || b > 0x7e {
+		if b, err = r.ReadByte(); err != nil {
+			return keyUnknown, err
+		}
+	}
+	return keyUnknown, nil
+}
+
+const editorHelp = "arrows or hjkl move  space toggles  0-9 labels  [ ] columns  { } rows  u undo  U redo  s save  r reload  q quit"
+
+// editor is a grid being drawn in the terminal. Every change searches the grid again, so its shapes are shown
+// colored by their unique shape as they are drawn.
+type editor struct {
+	grid       [][]int
+	x, y       int
+	undo, redo [][][]int
+	path       string
+	opts       search.Options
+	// view is the number of rows and columns of cells that fit on the screen, and top and left the first shown.
+	view      [2]int
+	top, left int
+	labels    [][]int
+	status    string
+	modified  bool
+	quitting  bool
+}
+
+// newEditor returns an editor for the grid saved at path, or a blank grid of the given size when there is none.
+func newEditor(path string, rows, cols int, opts search.Options) (*editor, error) {
+	e := &editor{path: path, opts: opts, view: [2]int{rows, cols}}
+	if err := e.load(); os.IsNotExist(err) {
+		e.grid = make([][]int, rows)
+		for y := range e.grid {
+			e.grid[y] = make([]int, cols)
+		}
+		e.status = "new grid " + path
+	} else if err != nil {
+		return nil, err
+	}
+	e.search()
+	return e, nil
+}
+
+// load reads the grid from its file.
+func (e *editor) load() error {
+	f, err := os.Open(e.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	g, err := parseText(f, format{labels: true})
+	if err != nil {
+		return inputError{err}
+	}
+	e.grid, e.modified = g, false
+	e.x, e.y = 0, 0
+	e.status = "loaded " + e.path
+	return nil
+}
+
+// save writes the grid to its file as text.
+func (e *editor) save() error {
+	var b strings.Builder
+	if err := writeGrid(&b, e.grid, "text", format{}); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(e.path, []byte(b.String()), 0644); err != nil {
+		return err
+	}
+	e.modified = false
+	e.status = "saved " + e.path
+	return nil
+}
+
+// search finds the shapes in the grid and labels each cell with its unique shape.
+func (e *editor) search() {
+	s, err := search.NewWithOptions(e.grid, e.opts)
+	if err != nil {
+		e.labels, e.status = nil, err.Error()
+		return
+	}
+	e.labels = s.Labels()
+	r := s.Result()
+	e.status = fmt.Sprintf("%d unique shapes in %d", len(r.Shapes), r.Components)
+}
+
+// change records the grid for undo before it is changed.
+func (e *editor) change() {
+	e.undo = append(e.undo, copyGrid(e.grid))
+	e.redo = nil
+	e.modified = true
+}
+
+func copyGrid(g [][]int) [][]int {
+	c := make([][]int, len(g))
+	for y, row := range g {
+		c[y] = append([]int(nil), row...)
+	}
+	return c
+}
+
+// handle acts on a key and reports whether the editor is done.
+func (e *editor) handle(k rune) bool {
+	quitting := e.quitting
+	e.quitting = false
+	rows, cols := len(e.grid), len(e.grid[0])
+	switch {
+	case k == keyUp || k == 'k':
+		e.y = (e.y + rows - 1) % rows
+	case k == keyDown || k == 'j':
+		e.y = (e.y + 1) % rows
+	case k == keyLeft || k == 'h':
+		e.x = (e.x + cols - 1) % cols
+	case k == keyRight || k == 'l':
+		e.x = (e.x + 1) % cols
+	case k == ' ' || k == 'x':
+		e.change()
+		if e.grid[e.y][e.x] == 0 {
+			e.grid[e.y][e.x] = 1
+		} else {
+			e.grid[e.y][e.x] = 0
+		}
+		e.search()
+	case k >= '0' && k <= '9':
+		e.change()
+		e.grid[e.y][e.x] = int(k - '0')
+		e.search()
+	case k == ']':
+		e.change()
+		for y := range e.grid {
+			e.grid[y] = append(e.grid[y], 0)
+		}
+		e.search()
+	case k == '[' && cols > 1:
+		e.change()
+		for y := range e.grid {
+			e.grid[y] = e.grid[y][:cols-1]
+		}
+		e.x = clamp(e.x, cols-1)
+		e.search()
+	case k == '}':
+		e.change()
+		e.grid = append(e.grid, make([]int, cols))
+		e.search()
+	case k == '{' && rows > 1:
+		e.change()
+		e.grid = e.grid[:rows-1]
+		e.y = clamp(e.y, rows-1)
+		e.search()
+	case k == 'u':
+		e.step(&e.undo, &e.redo, "undo")
+	case k == 'U' || k == keyCtrlR:
+		e.step(&e.redo, &e.undo, "redo")
+	case k == 's':
+		if err := e.save(); err != nil {
+			e.status = err.Error()
+		}
+	case k == 'r':
+		if err := e.load(); err != nil {
+			e.status = err.Error()
+			return false
+		}
+		e.undo, e.redo = nil, nil
+		e.search()
+	case k == 'q' || k == keyCtrlC:
+		if e.modified && !quitting {
+			e.status = "unsaved changes, press q again to quit"
+			e.quitting = true
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+// step goes back or forward a change, taking the grid from one stack and keeping the one it replaces on the other.
+func (e *editor) step(from, to *[][][]int, name string) {
+	if len(*from) == 0 {
+		e.status = "nothing to " + name
+		return
+	}
+	*to = append(*to, e.grid)
+	e.grid = (*from)[len(*from)-1]
+	*from = (*from)[:len(*from)-1]
+	e.modified = true
+	e.x, e.y = clamp(e.x, len(e.grid[0])), clamp(e.y, len(e.grid))
+	e.search()
+}
+
+// clamp keeps i below n.
+func clamp(i, n int) int {
+	if i >= n {
+		return n - 1
+	}
+	return i
+}
+
+// scroll moves the view so the cursor is on screen.
+func (e *editor) scroll() {
+	if e.y < e.top {
+		e.top = e.y
+	}
+	if e.y >= e.top+e.view[0] {
+		e.top = e.y - e.view[0] + 1
+	}
+	if e.x < e.left {
+		e.left = e.x
+	}
+	if e.x >= e.left+e.view[1] {
+		e.left = e.x - e.view[1] + 1
+	}
+}
+
+// draw writes the screen: the part of the grid in view, two characters to a cell, with cells colored by their
+// unique shape and the cursor in reverse video, then a status line and the keys.
+func (e *editor) draw(w io.Writer) {
+	e.scroll()
+	var b strings.Builder
+	b.WriteString(clearScreen)
+	for y := e.top; y < len(e.grid) && y < e.top+e.view[0]; y++ {
+		for x := e.left; x < len(e.grid[y]) && x < e.left+e.view[1]; x++ {
+			if x == e.x && y == e.y {
+				b.WriteString(reverse)
+			}
+			v, cell := e.grid[y][x], " ."
+			if v != 0 {
+				cell = fmt.Sprintf("%2d", v)
+			}
+			if e.labels != nil && e.labels[y][x] > 0 {
+				b.WriteString(color256.shade(e.labels[y][x] - 1))
+			}
+			b.WriteString(cell + reset)
+		}
+		b.WriteString("\r\n")
+	}
+	name := e.path
+	if e.modified {
+		name += " (modified)"
+	}
+	fmt.Fprintf(&b, "\r\n%d x %d  row %d column %d  %s  %s\r\n%s", len(e.grid), len(e.grid[0]), e.y+1, e.x+1, name,
+		e.status, editorHelp)
+	_, _ = io.WriteString(w, b.String())
+}
+
+// run draws the editor and acts on keys until it is done.
+func (e *editor) run(r io.Reader, w io.Writer) error {
+	keys := bufio.NewReader(r)
+	for {
+		e.draw(w)
+		k, err := readKey(keys)
+		if err != nil {
+			return err
+		}
+		if e.handle(k) {
+			return nil
+		}
+	}
+}
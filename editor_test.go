@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/murphybytes/shapes/search"
+)
+
+func TestReadKey(t *testing.T) {
+	var got []rune
+	r := bufio.NewReader(strings.NewReader("a\x1b[A\x1b[B\x1b[C\x1b[D\x1b[1;5Cb\x1bOAq"))
+	for {
+		k, err := readKey(r)
+		if err != nil {
+			break
+		}
+		got = append(got, k)
+	}
+	want := []rune{'a', keyUp, keyDown, keyRight, keyLeft, keyUnknown, 'b', keyUp, 'q'}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %v got %v", want, got)
+	}
+}
+
+func TestEditor(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shapes")
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	defer os.RemoveAll(dir)
+	saved := filepath.Join(dir, "saved.txt")
+	if err := ioutil.WriteFile(saved, []byte("1 0\n0 2\n"), 0600); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	tt := []struct {
+		path     string
+		keys     string
+		want     [][]int
+		wantDone bool
+		wantFile string
+	}{
+		{path: "new.txt", keys: " l l ", want: [][]int{{1, 1, 1}, {0, 0, 0}}},
+		{path: "new.txt", keys: "kh3", want: [][]int{{0, 0, 0}, {0, 0, 3}}},
+		{path: "new.txt", keys: "]}{[[[", want: [][]int{{0}, {0}}},
+		{path: "new.txt", keys: "  xuuuU", want: [][]int{{1, 0, 0}, {0, 0, 0}}},
+		{path: "new.txt", keys: " u U", want: [][]int{{1, 0, 0}, {0, 0, 0}}},
+		{path: "new.txt", keys: "x]]llllu", want: [][]int{{1, 0, 0, 0}, {0, 0, 0, 0}}},
+		{path: "new.txt", keys: "q", want: [][]int{{0, 0, 0}, {0, 0, 0}}, wantDone: true},
+		// a change has to be saved or quit twice
+		{path: "new.txt", keys: "xq", want: [][]int{{1, 0, 0}, {0, 0, 0}}},
+		{path: "new.txt", keys: "xqq", want: [][]int{{1, 0, 0}, {0, 0, 0}}, wantDone: true},
+		{path: "new.txt", keys: "xslqq", want: [][]int{{1, 0, 0}, {0, 0, 0}}, wantDone: true},
+		{path: saved, keys: "x]s", want: [][]int{{0, 0, 0}, {0, 2, 0}}, wantFile: "0 0 0\n0 2 0\n"},
+		{path: saved, keys: "x]rq", want: [][]int{{1, 0}, {0, 2}}, wantDone: true},
+	}
+	for i, tc := range tt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			path := tc.path
+			if path == saved {
+				if err := ioutil.WriteFile(saved, []byte("1 0\n0 2\n"), 0600); err != nil {
+					t.Fatal("unexpected error", err)
+				}
+			} else {
+				path = filepath.Join(dir, strconv.Itoa(i)+path)
+			}
+			e, err := newEditor(path, 2, 3, search.Options{})
+			if err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			done := false
+			for _, k := range tc.keys {
+				done = e.handle(k)
+			}
+			if done != tc.wantDone {
+				t.Fatalf("want done %v got %v", tc.wantDone, done)
+			}
+			if !reflect.DeepEqual(e.grid, tc.want) {
+				t.Fatalf("want %v got %v", tc.want, e.grid)
+			}
+			if tc.wantFile != "" {
+				b, err := ioutil.ReadFile(path)
+				if err != nil {
+					t.Fatal("unexpected error", err)
+				}
+				if string(b) != tc.wantFile {
+					t.Fatalf("want file %q got %q", tc.wantFile, b)
+				}
+			}
+		})
+	}
+}
+
+func TestDrawEditor(t *testing.T) {
+	e, err := newEditor(filepath.Join(os.TempDir(), "no-such-grid.txt"), 2, 3, search.Options{Topology: search.Plane})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	for _, k := range "x" + "j" + "2" + "ll" + "2" {
+		e.handle(k)
+	}
+	// only the cursor is drawn when the view is one cell
+	var b bytes.Buffer
+	e.view = [2]int{1, 1}
+	e.draw(&b)
+	got := b.String()
+	want := clearScreen + reverse + "\x1b[38;5;16;48;5;46m 2" + reset + "\r\n"
+	if !strings.HasPrefix(got, want) {
+		t.Fatalf("want prefix %q got %q", want, got)
+	}
+	if !strings.Contains(got, "2 x 3  row 2 column 3") || !strings.Contains(got, "2 unique shapes in 3") {
+		t.Fatalf("unexpected status in %q", got)
+	}
+}
@@ -0,0 +1,417 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/gif"  // register decoder
+	_ "image/jpeg" // register decoder
+	_ "image/png"  // register decoder
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/murphybytes/shapes/search"
+)
+
+const errorEmptyGrid = errorType("grid has no rows")
+const errorRaggedGrid = errorType("rows in grid differ in length")
+const errorGridTooLarge = errorType("grid has too many cells")
+const errorPoint = errorType("each line must hold a column and a row")
+const errorCompact = errorType("compact rows hold a digit or a -set or -unset character for each cell")
+const errorCompactValue = errorType("compact text holds values up to 9")
+const errorTriangle = errorType("triangles are drawn as ^ where the column and row add up to an even number, v where they are odd and . where empty")
+const errorHexIndent = errorType("hex rows start with a value in even rows and with one space before it in odd rows")
+
+// parseText reads a grid written one row per line with cells separated by spaces, as they are typed into the
+// interactive prompts. Blank lines and comments are skipped.
+func parseText(r io.Reader, f format) ([][]int, error) {
+	var grid [][]int
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<24)
+	for scanner.Scan() {
+		if f.skip(scanner.Text()) {
+			continue
+		}
+		row, err := f.textRow(scanner.Text())
+		if err != nil {
+			return nil, err
+		}
+		grid = append(grid, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := checkGrid(grid); err != nil {
+		return nil, err
+	}
+	return grid, nil
+}
+
+// textRow reads a line of text input as a row of cells.
+func (f format) textRow(line string) ([]int, error) {
+	fields := strings.Fields(line)
+	row := make([]int, len(fields))
+	for i, field := range fields {
+		v, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, err
+		}
+		row[i] = v
+	}
+	if err := f.check(row); err != nil {
+		return nil, err
+	}
+	return row, nil
+}
+
+// textRows reads text input as parseText does, passing each row on as a line as soon as it is read, so a stream
+// search sees only rows parseText would accept. The first row it would not stops the rows, and err holds why.
+type textRows struct {
+	scanner *bufio.Scanner
+	f       format
+	rows    int
+	cols    int
+	pending []byte
+	err     error
+}
+
+func newTextRows(r io.Reader, f format) *textRows {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, math.MaxInt32)
+	return &textRows{scanner: scanner, f: f}
+}
+
+func (t *textRows) Read(p []byte) (int, error) {
+	for len(t.pending) == 0 {
+		if t.err != nil {
+			return 0, t.err
+		}
+		if !t.scanner.Scan() {
+			t.err = t.scanner.Err()
+			if t.err == nil && t.rows == 0 {
+				t.err = errorEmptyGrid
+			}
+			if t.err == nil {
+				return 0, io.EOF
+			}
+			continue
+		}
+		line := t.scanner.Text()
+		if t.f.skip(line) {
+			continue
+		}
+		row, err := t.f.textRow(line)
+		switch {
+		case err != nil:
+			t.err = err
+		case t.rows > 0 && len(row) != t.cols:
+			t.err = errorRaggedGrid
+		default:
+			t.rows, t.cols = t.rows+1, len(row)
+			t.pending = append([]byte(line), '\n')
+		}
+	}
+	n := copy(p, t.pending)
+	t.pending = t.pending[n:]
+	return n, nil
+}
+
+// parseCompact reads a grid written one row per line with a character for each cell, either a digit for its value
+// or one of the format's set or unset characters, as in ..##. or 00110. Blank lines and comments are skipped.
+// Spaces around a row are dropped unless a space stands for empty cells. Then a line of spaces is a row of empty
+// cells, only empty lines and lines starting with a comment are skipped, and rows cut short, as editors leave them
+// when they drop trailing spaces, are filled out with empty cells.
+func parseCompact(r io.Reader, f format) ([][]int, error) {
+	set, unset := f.glyphs()
+	spaces := strings.ContainsRune(unset, ' ')
+	var grid [][]int
+	width := 0
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<24)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		switch {
+		case !spaces && f.skip(line):
+			continue
+		case !spaces:
+			line = strings.TrimSpace(line)
+		case line == "" || f.comment != "" && strings.HasPrefix(line, f.comment):
+			continue
+		}
+		row := make([]int, 0, len(line))
+		for _, c := range line {
+			switch {
+			case strings.ContainsRune(set, c):
+				row = append(row, 1)
+			case strings.ContainsRune(unset, c):
+				row = append(row, 0)
+			case c >= '0' && c <= '9':
+				row = append(row, int(c-'0'))
+			default:
+				return nil, fmt.Errorf("%s, found %q", errorCompact, c)
+			}
+		}
+		if err := f.check(row); err != nil {
+			return nil, err
+		}
+		if len(row) > width {
+			width = len(row)
+		}
+		grid = append(grid, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if spaces {
+		for i, row := range grid {
+			grid[i] = append(row, make([]int, width-len(row))...)
+		}
+	}
+	if err := checkGrid(grid); err != nil {
+		return nil, err
+	}
+	return grid, nil
+}
+
+// parseJSON reads a grid given as a JSON object with a "grid" array of rows.
+func parseJSON(r io.Reader, f format) ([][]int, error) {
+	var body struct {
+		Grid [][]int `json:"grid"`
+	}
+	if err := json.NewDecoder(r).Decode(&body); err != nil {
+		return nil, err
+	}
+	for _, row := range body.Grid {
+		if err := f.check(row); err != nil {
+			return nil, err
+		}
+	}
+	if err := checkGrid(body.Grid); err != nil {
+		return nil, err
+	}
+	return body.Grid, nil
+}
+
+// parseImage reads a PNG, GIF or JPEG image with one pixel per cell. Dark pixels are set and light or transparent
+// pixels are empty. Images with more than maxCells pixels are refused before they are decoded.
+func parseImage(r io.ReadSeeker, maxCells int) ([][]int, error) {
+	cfg, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width*cfg.Height > maxCells {
+		return nil, errorGridTooLarge
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return nil, err
+	}
+	b := img.Bounds()
+	grid := make([][]int, b.Dy())
+	for y := range grid {
+		grid[y] = make([]int, b.Dx())
+		for x := range grid[y] {
+			cr, cg, cb, ca := img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+			// Weights are the ITU-R BT.601 luma coefficients, scaled so a fully opaque pixel sums to 0xffff.
+			luma := (299*cr + 587*cg + 114*cb) / 1000
+			if ca > 0x7fff && luma < ca/2 {
+				grid[y][x] = 1
+			}
+		}
+	}
+	return grid, nil
+}
+
+// parseVolume reads a volume written slice by slice, each slice a grid written as parseText reads it. One or more
+// blank lines end a slice. Comments are skipped.
+func parseVolume(r io.Reader, f format) (search.Volume, error) {
+	var volume search.Volume
+	var slice [][]int
+	end := func() error {
+		if len(slice) == 0 {
+			return nil
+		}
+		if err := checkGrid(slice); err != nil {
+			return err
+		}
+		if len(volume) > 0 && (len(slice) != len(volume[0]) || len(slice[0]) != len(volume[0][0])) {
+			return errorRaggedGrid
+		}
+		volume, slice = append(volume, slice), nil
+		return nil
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<24)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 0 && f.skip(scanner.Text()) {
+			continue
+		}
+		if len(fields) == 0 {
+			if err := end(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		row := make([]int, len(fields))
+		for i, field := range fields {
+			v, err := strconv.Atoi(field)
+			if err != nil {
+				return nil, err
+			}
+			row[i] = v
+		}
+		if err := f.check(row); err != nil {
+			return nil, err
+		}
+		slice = append(slice, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := end(); err != nil {
+		return nil, err
+	}
+	if len(volume) == 0 {
+		return nil, errorEmptyGrid
+	}
+	return volume, nil
+}
+
+// parsePoints reads the column and row of each set cell of an unbounded plane, one cell per line, separated by a
+// comma or spaces. Blank lines are skipped.
+func parsePoints(r io.Reader) ([][2]int, error) {
+	var cells [][2]int
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(strings.Replace(scanner.Text(), ",", " ", -1))
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, errorPoint
+		}
+		var c [2]int
+		for i, field := range fields {
+			v, err := strconv.Atoi(field)
+			if err != nil {
+				return nil, err
+			}
+			c[i] = v
+		}
+		cells = append(cells, c)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(cells) == 0 {
+		return nil, errorEmptyGrid
+	}
+	return cells, nil
+}
+
+// parseTriangles reads a triangular grid drawn one row per line, with ^ for a set triangle pointing up, v for one
+// pointing down and . for an empty cell. Triangles must point the way the lattice does where they are drawn, which
+// catches rows that have slipped a cell. Blank lines are skipped.
+func parseTriangles(r io.Reader) ([][]int, error) {
+	var grid [][]int
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<24)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		y := len(grid)
+		row := make([]int, len(line))
+		for x, c := range []byte(line) {
+			up := (x+y)%2 == 0
+			switch {
+			case c == '.':
+			case c == '^' && up, c == 'v' && !up:
+				row[x] = 1
+			default:
+				return nil, errorTriangle
+			}
+		}
+		grid = append(grid, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := checkGrid(grid); err != nil {
+		return nil, err
+	}
+	return grid, nil
+}
+
+// parseHex reads a hex grid drawn one row per line with cells separated by spaces and odd rows indented one space,
+// so each cell sits between the two it touches in the rows above and below. Rows must be indented the way the
+// lattice staggers them, which catches rows that have slipped half a cell. Blank lines and comments are skipped.
+func parseHex(r io.Reader, f format) ([][]int, error) {
+	var grid [][]int
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<24)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t")
+		if f.skip(line) {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		if indent != len(grid)%2 {
+			return nil, errorHexIndent
+		}
+		fields := strings.Fields(line)
+		row := make([]int, len(fields))
+		for i, field := range fields {
+			v, err := strconv.Atoi(field)
+			if err != nil {
+				return nil, err
+			}
+			row[i] = v
+		}
+		if err := f.check(row); err != nil {
+			return nil, err
+		}
+		grid = append(grid, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := checkGrid(grid); err != nil {
+		return nil, err
+	}
+	return grid, nil
+}
+
+// check returns an error if the row holds a value the format does not allow.
+func (f format) check(row []int) error {
+	for _, v := range row {
+		switch {
+		case f.labels && v < 0:
+			return errorNegativeColumn
+		case f.labels:
+		case v != 0 && v != 1:
+			return errorIllegalColumn
+		}
+	}
+	return nil
+}
+
+func checkGrid(grid [][]int) error {
+	if len(grid) == 0 || len(grid[0]) == 0 {
+		return errorEmptyGrid
+	}
+	for _, row := range grid {
+		if len(row) != len(grid[0]) {
+			return errorRaggedGrid
+		}
+	}
+	return nil
+}
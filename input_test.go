@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParseText(t *testing.T) {
+	tt := []struct {
+		input   string
+		labels  bool
+		comment string
+		want    [][]int
+		err     error
+	}{
+		{input: "1 0 1\n\n0 1 0\n", want: [][]int{{1, 0, 1}, {0, 1, 0}}},
+		{input: "// a comment\n1 0 1\n  // another\n0 1 0\n", comment: "//", want: [][]int{{1, 0, 1}, {0, 1, 0}}},
+		{input: "1 0 1\n0 1\n", err: errorRaggedGrid},
+		{input: "\n\n", err: errorEmptyGrid},
+		{input: "1 0 2\n", err: errorIllegalColumn},
+		{input: "1 0 2\n", labels: true, want: [][]int{{1, 0, 2}}},
+	}
+	for i, tc := range tt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			got, err := parseText(bytes.NewBufferString(tc.input), format{labels: tc.labels, comment: tc.comment})
+			if err != tc.err {
+				t.Fatalf("want error %v got %v", tc.err, err)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("want %v got %v", tc.want, got)
+			}
+			for j := range got {
+				assertEqual(t, got[j], tc.want[j])
+			}
+			// a stream reads the rows that parseText accepts and fails where it does
+			rows, err := ioutil.ReadAll(newTextRows(bytes.NewBufferString(tc.input), format{labels: tc.labels, comment: tc.comment}))
+			if err != tc.err {
+				t.Fatalf("want stream error %v got %v", tc.err, err)
+			}
+			if streamed, _ := parseText(bytes.NewBuffer(rows), format{labels: true}); err == nil && !reflect.DeepEqual(streamed, got) {
+				t.Fatalf("want streamed rows %v got %v", got, streamed)
+			}
+		})
+	}
+}
+
+func TestParseCompact(t *testing.T) {
+	tt := []struct {
+		input string
+		f     format
+		want  [][]int
+		err   string
+	}{
+		{input: "..##.\n#...#\n", want: [][]int{{0, 0, 1, 1, 0}, {1, 0, 0, 0, 1}}},
+		{input: "00110\r\n\n  10001  \n", want: [][]int{{0, 0, 1, 1, 0}, {1, 0, 0, 0, 1}}},
+		{input: "# puzzle\n@ @\n @ \n", f: format{set: "@", unset: " ", comment: "#"}, want: [][]int{{1, 0, 1}, {0, 1, 0}}},
+		// a row of spaces is a row of empty cells, and rows cut short are filled out
+		{input: "#   \n    \n#\n", f: format{set: "#", unset: " "}, want: [][]int{{1, 0, 0, 0}, {0, 0, 0, 0}, {1, 0, 0, 0}}},
+		{input: "xo\nOX\n", f: format{set: "xX", unset: "oO"}, want: [][]int{{1, 0}, {0, 1}}},
+		{input: ".2\n", f: format{labels: true}, want: [][]int{{0, 2}}},
+		{input: ".2\n", err: errorIllegalColumn.Error()},
+		{input: "#.\n#\n", err: errorRaggedGrid.Error()},
+		{input: "#?\n", err: `compact rows hold a digit or a -set or -unset character for each cell, found '?'`},
+		{input: "\n", err: errorEmptyGrid.Error()},
+	}
+	for i, tc := range tt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			got, err := parseCompact(strings.NewReader(tc.input), tc.f)
+			if err != nil || tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf("want error %q got %v", tc.err, err)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("want %v got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestParseVolume(t *testing.T) {
+	tt := []struct {
+		input  string
+		slices int
+		err    error
+	}{
+		{input: "1 0\n0 1\n\n\n0 0\n1 1\n", slices: 2},
+		{input: "1 0\n\n1 0\n0 0\n", err: errorRaggedGrid},
+		{input: "1 0\n0\n", err: errorRaggedGrid},
+		{input: "1 2\n", err: errorIllegalColumn},
+		{input: "\n\n", err: errorEmptyGrid},
+	}
+	for i, tc := range tt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			got, err := parseVolume(bytes.NewBufferString(tc.input), format{})
+			if err != tc.err {
+				t.Fatalf("want error %v got %v", tc.err, err)
+			}
+			if len(got) != tc.slices {
+				t.Fatalf("want %d slices got %d", tc.slices, len(got))
+			}
+		})
+	}
+}
+
+func TestParsePoints(t *testing.T) {
+	tt := []struct {
+		input string
+		want  [][2]int
+		err   error
+	}{
+		{input: "1 2\n\n-5,1000000000000\n", want: [][2]int{{1, 2}, {-5, 1000000000000}}},
+		{input: "1 2 3\n", err: errorPoint},
+		{input: "\n", err: errorEmptyGrid},
+	}
+	for i, tc := range tt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			got, err := parsePoints(bytes.NewBufferString(tc.input))
+			if err != tc.err {
+				t.Fatalf("want error %v got %v", tc.err, err)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("want %v got %v", tc.want, got)
+			}
+			for j := range got {
+				if got[j] != tc.want[j] {
+					t.Fatalf("want %v got %v", tc.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestParseTriangles(t *testing.T) {
+	tt := []struct {
+		input string
+		want  [][]int
+		err   error
+	}{
+		{input: "^v.\n\nv^v\n", want: [][]int{{1, 1, 0}, {1, 1, 1}}},
+		{input: "v..\n", err: errorTriangle},
+		{input: "^v\n.\n", err: errorRaggedGrid},
+		{input: "\n", err: errorEmptyGrid},
+	}
+	for i, tc := range tt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			got, err := parseTriangles(bytes.NewBufferString(tc.input))
+			if err != tc.err {
+				t.Fatalf("want error %v got %v", tc.err, err)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("want %v got %v", tc.want, got)
+			}
+			for j := range got {
+				assertEqual(t, got[j], tc.want[j])
+			}
+		})
+	}
+}
+
+func TestParseHex(t *testing.T) {
+	tt := []struct {
+		input string
+		f     format
+		want  [][]int
+		err   error
+	}{
+		{input: "1 1 0\n\n 0 1 1\n1 0 0\n", want: [][]int{{1, 1, 0}, {0, 1, 1}, {1, 0, 0}}},
+		{input: "// a comment\n0 2\n 3 0\n", f: format{comment: "//", labels: true}, want: [][]int{{0, 2}, {3, 0}}},
+		{input: "1 1\n0 1\n", err: errorHexIndent},
+		{input: " 1 1\n", err: errorHexIndent},
+		{input: "1 2\n", err: errorIllegalColumn},
+		{input: "1 1\n 1\n", err: errorRaggedGrid},
+		{input: "\n", err: errorEmptyGrid},
+	}
+	for i, tc := range tt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			got, err := parseHex(bytes.NewBufferString(tc.input), tc.f)
+			if err != tc.err {
+				t.Fatalf("want error %v got %v", tc.err, err)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("want %v got %v", tc.want, got)
+			}
+			for j := range got {
+				assertEqual(t, got[j], tc.want[j])
+			}
+		})
+	}
+}
+
+func TestParseImage(t *testing.T) {
+	want := [][]int{
+		{1, 0, 1},
+		{0, 1, 1},
+	}
+	got, err := parseImage(bytes.NewReader(pngGrid(t, want)), 6)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	for i := range want {
+		assertEqual(t, got[i], want[i])
+	}
+
+	if _, err := parseImage(bytes.NewReader(pngGrid(t, want)), 5); err != errorGridTooLarge {
+		t.Fatalf("want %v got %v", errorGridTooLarge, err)
+	}
+}
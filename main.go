@@ -1,20 +1,43 @@
 package main
 
 import (
-	"log"
+	"io"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/murphybytes/shapes/search"
 )
 
+const errorAffine = errorType("affine transform must be six comma separated numbers")
+
+// searchResult is what the program writes out from a search.
+type searchResult interface {
+	Print(w io.Writer)
+	PrintContours(w io.Writer)
+	WriteGeoJSON(w io.Writer, a search.Affine) error
+	WriteWKT(w io.Writer, a search.Affine) error
+	WriteSVG(w io.Writer) error
+	Result() search.Result
+	Keys() []string
+}
+
 func main() {
-	g, err := readGrid(os.Stdin, os.Stdout)
-	if err != nil {
-		log.Fatalf("Program exited %q", err)
+	os.Exit(cli{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}.run(os.Args[1:]))
+}
+
+func parseAffine(s string) (search.Affine, error) {
+	var a search.Affine
+	fields := strings.Split(s, ",")
+	if len(fields) != len(a) {
+		return a, errorAffine
 	}
-	s, err := search.New(g)
-	if err != nil {
-		log.Fatalf("search returned error %q", err)
+	for i, f := range fields {
+		v, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+		if err != nil {
+			return a, errorAffine
+		}
+		a[i] = v
 	}
-	s.Print(os.Stdout)
+	return a, nil
 }
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/murphybytes/shapes/search"
+)
+
+func TestParseAffine(t *testing.T) {
+	tt := []struct {
+		input string
+		want  search.Affine
+		err   bool
+	}{
+		{"0,1,0,0,0,1", search.Identity, false},
+		{"100.5, 10, 0, 200, 0, -10", search.Affine{100.5, 10, 0, 200, 0, -10}, false},
+		{"0,1,0,0,0", search.Affine{}, true},
+		{"0,1,0,0,0,x", search.Affine{}, true},
+	}
+	for i, tc := range tt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			got, err := parseAffine(tc.input)
+			if tc.err {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			if got != tc.want {
+				t.Fatalf("want %v got %v", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package search
+
+import (
+	"context"
+	"math/bits"
+)
+
+const errorNotBinary = stateError("bit grids only hold ones and zeros")
+
+const wordBits = 64
+
+// Bits is a grid of ones and zeros packed one bit to a cell. Each row starts on a new word, so a row can be
+// scanned a word at a time and empty stretches skipped.
+type Bits struct {
+	rows, cols int
+	// stride is the number of words in a row.
+	stride int
+	words  []uint64
+}
+
+// NewBits returns an empty grid of the given size.
+func NewBits(rows, cols int) *Bits {
+	stride := (cols + wordBits - 1) / wordBits
+	return &Bits{rows: rows, cols: cols, stride: stride, words: make([]uint64, rows*stride)}
+}
+
+// BitsFromInts packs a grid of ones and zeros.
+func BitsFromInts(g [][]int) (*Bits, error) {
+	if len(g) == 0 {
+		return nil, errorNoRows
+	}
+	if len(g[0]) == 0 {
+		return nil, errorNoCols
+	}
+	b := NewBits(len(g), len(g[0]))
+	for y, row := range g {
+		if len(row) != b.cols {
+			return nil, errorRagged
+		}
+		for x, v := range row {
+			switch v {
+			case unset:
+			case set:
+				b.Set(x, y, true)
+			default:
+				return nil, errorNotBinary
+			}
+		}
+	}
+	return b, nil
+}
+
+// Ints unpacks the grid into one int per cell.
+func (b *Bits) Ints() [][]int {
+	g := make([][]int, b.rows)
+	for y := range g {
+		g[y] = make([]int, b.cols)
+		for x := range g[y] {
+			if b.Get(x, y) {
+				g[y][x] = set
+			}
+		}
+	}
+	return g
+}
+
+// Rows returns the number of rows in the grid.
+func (b *Bits) Rows() int { return b.rows }
+
+// Cols returns the number of columns in the grid.
+func (b *Bits) Cols() int { return b.cols }
+
+// Get reports whether the cell in column x and row y is set.
+func (b *Bits) Get(x, y int) bool {
+	return b.words[y*b.stride+x/wordBits]&(1<<uint(x%wordBits)) != 0
+}
+
+// Set sets or clears the cell in column x and row y.
+func (b *Bits) Set(x, y int, v bool) {
+	i, mask := y*b.stride+x/wordBits, uint64(1)<<uint(x%wordBits)
+	if v {
+		b.words[i] |= mask
+	} else {
+		b.words[i] &^= mask
+	}
+}
+
+// Value returns one if the cell in column x and row y is set and zero otherwise.
+func (b *Bits) Value(x, y int) int {
+	if b.Get(x, y) {
+		return set
+	}
+	return unset
+}
+
+// Next returns the first set cell at or after column x of row y in row order, skipping a word at a time over
+// empty cells.
+func (b *Bits) Next(x, y int) (int, int, bool) {
+	return b.next(x, y, nil)
+}
+
+// next is Next passing over the cells set in skip as well, a word at a time. Skip is the same size as b or nil.
+func (b *Bits) next(x, y int, skip *Bits) (int, int, bool) {
+	for ; y < b.rows; y, x = y+1, 0 {
+		for w := x / wordBits; w < b.stride; w++ {
+			word := b.words[y*b.stride+w]
+			if skip != nil {
+				word &^= skip.words[y*b.stride+w]
+			}
+			if w == x/wordBits {
+				word &^= (1 << uint(x%wordBits)) - 1
+			}
+			if word != 0 {
+				return w*wordBits + bits.TrailingZeros64(word), y, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+func (b *Bits) mark(p point)        { b.Set(p.x, p.y, true) }
+func (b *Bits) marked(p point) bool { return b.Get(p.x, p.y) }
+
+// NewFromBits finds the unique shapes in a bit packed grid of ones and zeros. The search works on the packed grid
+// directly and gives the same result as New on the unpacked grid.
+func NewFromBits(ctx context.Context, b *Bits, opts Options) (*state, error) {
+	return NewFromGrid(ctx, b, opts)
+}
@@ -0,0 +1,199 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func TestBitsRoundTrip(t *testing.T) {
+	grid := randomGrid(rand.New(rand.NewSource(5)), 7, 130, 1, 0.5)
+	b, err := BitsFromInts(grid)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if b.Rows() != 7 || b.Cols() != 130 || b.stride != 3 {
+		t.Fatalf("unexpected size %dx%d stride %d", b.Rows(), b.Cols(), b.stride)
+	}
+	got := b.Ints()
+	for i := range grid {
+		for j := range grid[i] {
+			if got[i][j] != grid[i][j] {
+				t.Fatalf("cell %d,%d want %d got %d", j, i, grid[i][j], got[i][j])
+			}
+		}
+	}
+
+	tt := []struct {
+		grid [][]int
+		err  error
+	}{
+		{nil, errorNoRows},
+		{[][]int{{}}, errorNoCols},
+		{[][]int{{1, 0}, {1}}, errorRagged},
+		{[][]int{{1, 2}}, errorNotBinary},
+	}
+	for i, tc := range tt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			if _, err := BitsFromInts(tc.grid); err != tc.err {
+				t.Fatalf("want %v got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestBitsNext(t *testing.T) {
+	b := NewBits(3, 200)
+	b.Set(150, 0, true)
+	b.Set(3, 2, true)
+	b.Set(199, 2, true)
+
+	want := []point{{150, 0}, {3, 2}, {199, 2}}
+	var got []point
+	for x, y, ok := b.Next(0, 0); ok; x, y, ok = b.Next(x+1, y) {
+		got = append(got, point{x, y})
+	}
+	if len(got) != len(want) {
+		t.Fatalf("want %v got %v", want, got)
+	}
+	for i := range want {
+		if !got[i].match(want[i]) {
+			t.Fatalf("want %v got %v", want, got)
+		}
+	}
+
+	// visited cells are passed over along with the empty ones
+	skip := NewBits(3, 200)
+	skip.Set(150, 0, true)
+	skip.Set(3, 2, true)
+	if x, y, ok := b.next(0, 0, skip); !ok || x != 199 || y != 2 {
+		t.Fatalf("want 199, 2 got %d, %d, %v", x, y, ok)
+	}
+	skip.Set(199, 2, true)
+	if _, _, ok := b.next(0, 0, skip); ok {
+		t.Fatal("want no cells left")
+	}
+}
+
+func TestNewFromBits(t *testing.T) {
+	r := rand.New(rand.NewSource(6))
+	opts := []Options{
+		{},
+		{Topology: Plane},
+		{Connectivity: EightWay, FillHoles: true},
+	}
+	for i := 0; i < 30; i++ {
+		grid := randomGrid(r, 1+r.Intn(20), 1+r.Intn(100), 1, 0.3+r.Float64()*0.4)
+		for j, o := range opts {
+			t.Run(strconv.Itoa(i)+"/"+strconv.Itoa(j), func(t *testing.T) {
+				want, err := NewWithOptions(grid, o)
+				if err != nil {
+					t.Fatal("unexpected error", err)
+				}
+				b, err := BitsFromInts(grid)
+				if err != nil {
+					t.Fatal("unexpected error", err)
+				}
+				got, err := NewFromBits(context.Background(), b, o)
+				if err != nil {
+					t.Fatal("unexpected error", err)
+				}
+				var ww, gw bytes.Buffer
+				want.Print(&ww)
+				got.Print(&gw)
+				if ww.String() != gw.String() {
+					t.Fatalf("want\n%s\ngot\n%s", ww.String(), gw.String())
+				}
+				if len(want.components) != len(got.components) {
+					t.Fatalf("want %d components got %d", len(want.components), len(got.components))
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkGridMemory(b *testing.B) {
+	const rows, cols = 1000, 1000
+	b.Run("ints", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			g := make([][]int, rows)
+			for y := range g {
+				g[y] = make([]int, cols)
+			}
+		}
+	})
+	b.Run("bits", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			NewBits(rows, cols)
+		}
+	})
+}
+
+func BenchmarkSearchSparse(b *testing.B) {
+	grid := randomGrid(rand.New(rand.NewSource(7)), 1000, 1000, 1, 0.01)
+	packed, err := BitsFromInts(grid)
+	if err != nil {
+		b.Fatal(err)
+	}
+	sparse := sparseGrid(grid)
+	b.Run("ints", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := New(grid); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("bits", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := NewFromBits(context.Background(), packed, Options{}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("sparse", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := NewFromGrid(context.Background(), sparse, Options{}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkLabelSparse(b *testing.B) {
+	grid := randomGrid(rand.New(rand.NewSource(7)), 1000, 1000, 1, 0.01)
+	packed, err := BitsFromInts(grid)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Run("ints", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			s, err := newState(grid, Options{})
+			if err != nil {
+				b.Fatal(err)
+			}
+			if err := s.scan(context.Background()); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("bits", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			s, err := newGridState(packed, Options{})
+			if err != nil {
+				b.Fatal(err)
+			}
+			if err := s.scan(context.Background()); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,186 @@
+package search
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// chain holds the offset for each Freeman chain code. Codes count counter clockwise from east, with y growing down
+// the grid, so 2 is a step up a row.
+var chain = []point{
+	{1, 0}, {1, -1}, {0, -1}, {-1, -1}, {-1, 0}, {-1, 1}, {0, 1}, {1, 1},
+}
+
+// contour is a closed boundary running through the centers of the cells along the edge of a shape.
+type contour struct {
+	start point
+	// codes are the Freeman chain codes of each step from start back around to start.
+	codes []int
+}
+
+func (c contour) String() string {
+	var b strings.Builder
+	for _, code := range c.codes {
+		fmt.Fprint(&b, code)
+	}
+	return fmt.Sprintf("(%d,%d) %s", c.start.x, c.start.y, b.String())
+}
+
+// vertices returns the cells where the contour changes direction, beginning with start, as a polygon.
+func (c contour) vertices() []point {
+	vs := []point{c.start}
+	p := c.start
+	for i, code := range c.codes {
+		p = point{p.x + chain[code].x, p.y + chain[code].y}
+		if i+1 < len(c.codes) && c.codes[i+1] != code {
+			vs = append(vs, p)
+		}
+	}
+	return vs
+}
+
+// contours traces the outer boundary of the shape clockwise and the inner boundary around each of its holes, in
+// the coordinates of the shape's upper left corner. A hole made of parts that only meet at corners has an inner
+// contour around each part.
+func (s shape) contours() (outer contour, inner []contour) {
+	o := origin(s.points)
+	cells := make(map[point]bool)
+	for _, p := range s.points {
+		cells[point{p.x - o.x, p.y - o.y}] = true
+	}
+	first := normalize(s.points)[0]
+	outer = trace(func(p point) bool { return cells[p] }, first, point{first.x - 1, first.y})
+
+	for _, h := range s.holes {
+		for _, part := range splitParts(h, o) {
+			inPart := make(map[point]bool)
+			for _, p := range part {
+				inPart[p] = true
+			}
+			top := part[0]
+			inner = append(inner, trace(func(p point) bool { return !inPart[p] }, point{top.x, top.y - 1}, top))
+		}
+	}
+	return outer, inner
+}
+
+// splitParts moves points by -o and breaks them into groups connected through edges, each sorted by row then column.
+func splitParts(ps []point, o point) [][]point {
+	remaining := make(map[point]bool)
+	for _, p := range ps {
+		remaining[point{p.x - o.x, p.y - o.y}] = true
+	}
+	var parts [][]point
+	for _, p := range ps {
+		start := point{p.x - o.x, p.y - o.y}
+		if !remaining[start] {
+			continue
+		}
+		delete(remaining, start)
+		part := []point{start}
+		for i := 0; i < len(part); i++ {
+			for _, dir := range []direction{up, right, down, left} {
+				n := nextPoint(part[i], dir)
+				if remaining[n] {
+					delete(remaining, n)
+					part = append(part, n)
+				}
+			}
+		}
+		sort.Slice(part, func(i, j int) bool {
+			if part[i].y != part[j].y {
+				return part[i].y < part[j].y
+			}
+			return part[i].x < part[j].x
+		})
+		parts = append(parts, part)
+	}
+	return parts
+}
+
+// trace follows the boundary of the cells where inside is true using Moore neighbor tracing. It starts on start,
+// having arrived from back, which must be outside. Tracing stops once a step repeats, and the repeating cycle of
+// steps is returned beginning at start.
+func trace(inside func(point) bool, start, back point) contour {
+	type step struct{ at, back point }
+	var steps []step
+	var codes []int
+	seen := make(map[step]int)
+	at := start
+	for {
+		curr := step{at, back}
+		if i, ok := seen[curr]; ok {
+			steps, codes = steps[i:], codes[i:]
+			break
+		}
+		seen[curr] = len(steps)
+		steps = append(steps, curr)
+
+		d := code(at, back)
+		moved := false
+		for i := 1; i <= 8; i++ {
+			k := ((d-i)%8 + 8) % 8
+			n := point{at.x + chain[k].x, at.y + chain[k].y}
+			if inside(n) {
+				prev := chain[(k+1)%8]
+				back = point{at.x + prev.x, at.y + prev.y}
+				at = n
+				codes = append(codes, k)
+				moved = true
+				break
+			}
+		}
+		if !moved {
+			return contour{start: start}
+		}
+	}
+
+	for i, st := range steps {
+		if st.at.match(start) {
+			return contour{start: start, codes: append(codes[i:], codes[:i]...)}
+		}
+	}
+	return contour{start: steps[0].at, codes: codes}
+}
+
+// code returns the chain code of the step from b to its neighbor e.
+func code(b, e point) int {
+	d := point{e.x - b.x, e.y - b.y}
+	for i, c := range chain {
+		if c.match(d) {
+			return i
+		}
+	}
+	panic(fmt.Sprint("no chain code from ", b, " to ", e))
+}
+
+// PrintContours writes each unique shape followed by its outer and inner contours as Freeman chain codes and as
+// polygon vertices. Contours are only traced on square lattices; on others the shapes are written alone.
+func (s state) PrintContours(w io.Writer) {
+	s.printEach(w, func(shp shape) {
+		newCols := s.draw(w, shp)
+		if s.opts.Lattice != Square {
+			fmt.Fprintln(w, strings.Repeat("-", newCols+len(leftPadding)))
+			return
+		}
+		outer, inner := s.laidOut(shp).contours()
+		outer.start = s.unbounded(outer.start)
+		printContour(w, "outer", outer)
+		for _, c := range inner {
+			c.start = s.unbounded(c.start)
+			printContour(w, "inner", c)
+		}
+		fmt.Fprintln(w, strings.Repeat("-", newCols+len(leftPadding)))
+	})
+}
+
+func printContour(w io.Writer, kind string, c contour) {
+	fmt.Fprintf(w, "%s%s: %s\n", leftPadding, kind, c)
+	fmt.Fprintf(w, "%svertices:", leftPadding)
+	for _, v := range c.vertices() {
+		fmt.Fprintf(w, " (%d,%d)", v.x, v.y)
+	}
+	fmt.Fprintln(w)
+}
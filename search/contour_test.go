@@ -0,0 +1,125 @@
+package search
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+)
+
+func TestContours(t *testing.T) {
+	tt := []struct {
+		grid         [][]int
+		wantOuter    string
+		wantVertices []point
+		wantInner    []string
+	}{
+		{
+			grid: [][]int{
+				{0, 0, 0},
+				{0, 1, 0},
+				{0, 0, 0},
+			},
+			wantOuter:    "(0,0) ",
+			wantVertices: []point{{0, 0}},
+		},
+		{
+			grid: [][]int{
+				{0, 0, 0, 0, 0},
+				{0, 1, 1, 1, 0},
+				{0, 1, 0, 1, 0},
+				{0, 1, 1, 1, 0},
+				{0, 0, 0, 0, 0},
+			},
+			wantOuter:    "(0,0) 00664422",
+			wantVertices: []point{{0, 0}, {2, 0}, {2, 2}, {0, 2}},
+			wantInner:    []string{"(1,0) 5713"},
+		},
+		{
+			grid: [][]int{
+				{0, 0, 0, 0},
+				{0, 1, 0, 0},
+				{0, 1, 0, 0},
+				{0, 1, 1, 0},
+				{0, 0, 0, 0},
+			},
+			wantOuter:    "(0,0) 67422",
+			wantVertices: []point{{0, 0}, {0, 1}, {1, 2}, {0, 2}},
+		},
+		{
+			// the shape crosses the top and bottom edges, so its contour is traced where it joins up
+			grid: [][]int{
+				{0, 1, 0},
+				{0, 0, 0},
+				{0, 1, 0},
+			},
+			wantOuter:    "(0,0) 62",
+			wantVertices: []point{{0, 0}, {0, 1}},
+		},
+	}
+
+	for i, tc := range tt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			s, err := New(tc.grid)
+			if err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			outer, inner := s.shapes[0].contours()
+			if outer.String() != tc.wantOuter {
+				t.Fatalf("want outer %q got %q", tc.wantOuter, outer)
+			}
+			vs := outer.vertices()
+			if len(vs) != len(tc.wantVertices) {
+				t.Fatalf("want vertices %v got %v", tc.wantVertices, vs)
+			}
+			for j := range vs {
+				if !vs[j].match(tc.wantVertices[j]) {
+					t.Fatalf("want vertices %v got %v", tc.wantVertices, vs)
+				}
+			}
+			if len(inner) != len(tc.wantInner) {
+				t.Fatalf("want %d inner contours got %d", len(tc.wantInner), len(inner))
+			}
+			for j := range inner {
+				if inner[j].String() != tc.wantInner[j] {
+					t.Fatalf("want inner %q got %q", tc.wantInner[j], inner[j])
+				}
+			}
+		})
+	}
+}
+
+func TestPrintContours(t *testing.T) {
+	s, err := New([][]int{
+		{0, 0, 0, 0},
+		{0, 1, 1, 0},
+		{0, 0, 0, 0},
+	})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	var w bytes.Buffer
+	s.PrintContours(&w)
+	want := "    XX\n    outer: (0,0) 04\n    vertices: (0,0) (1,0)\n------\n"
+	if w.String() != want {
+		t.Logf("want %q", want)
+		t.Logf("got  %q", w.String())
+		t.Fatal()
+	}
+
+	// a shape wrapping all the way around is traced as it is drawn
+	s, err = New([][]int{
+		{1, 1},
+		{1, 1},
+	})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	w.Reset()
+	s.PrintContours(&w)
+	want = "    XX\n    XX\n    outer: (0,0) 0642\n    vertices: (0,0) (1,0) (1,1) (0,1)\n------\n"
+	if w.String() != want {
+		t.Logf("want %q", want)
+		t.Logf("got  %q", w.String())
+		t.Fatal()
+	}
+}
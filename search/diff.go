@@ -0,0 +1,187 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Diff is how the shapes and cells of a grid changed from an old version to a new one.
+type Diff struct {
+	// Removed are the unique shapes of the old grid that the new grid lacks, Added the unique shapes of the new grid
+	// that the old grid lacks and Changed those of the new grid that occur a different number of times in each.
+	Removed []ShapeDiff `json:"removed"`
+	Added   []ShapeDiff `json:"added"`
+	Changed []ShapeDiff `json:"changed"`
+	// Rows and Cols are the size the grids are compared at, the larger of each. The smaller grid is taken to hold
+	// empty cells beyond its edges.
+	Rows int `json:"rows"`
+	Cols int `json:"cols"`
+	// Cells are the cells that differ, in row order.
+	Cells []CellDiff `json:"cells"`
+
+	old, new *state
+	// grid is the new grid.
+	grid [][]int
+}
+
+// ShapeDiff is a unique shape that was removed, added or changed.
+type ShapeDiff struct {
+	// Index is the position of the shape among the unique shapes of Result for the old grid when it was removed,
+	// and for the new grid otherwise.
+	Index int         `json:"index"`
+	Shape ShapeResult `json:"shape"`
+	// Old and New are the number of times the shape occurs in each grid.
+	Old int `json:"old"`
+	New int `json:"new"`
+}
+
+// CellDiff is a cell whose value differs between the grids.
+type CellDiff struct {
+	X   int `json:"x"`
+	Y   int `json:"y"`
+	Old int `json:"old"`
+	New int `json:"new"`
+}
+
+// Compare searches an old and a new version of a grid with the same options and returns how they differ. Shapes
+// are the same when they are duplicates under the options, so a shape that only moved is unchanged. Grids are
+// preprocessed first when the options ask for it, and their cells compared after.
+func Compare(ctx context.Context, oldGrid, newGrid [][]int, opts Options) (*Diff, error) {
+	if len(opts.Preprocess) > 0 {
+		var err error
+		if oldGrid, err = opts.Preprocess.Apply(ctx, oldGrid, opts); err != nil {
+			return nil, err
+		}
+		if newGrid, err = opts.Preprocess.Apply(ctx, newGrid, opts); err != nil {
+			return nil, err
+		}
+		opts.Preprocess = nil
+	}
+	o, err := NewWithContext(ctx, oldGrid, opts)
+	if err != nil {
+		return nil, err
+	}
+	n, err := NewWithContext(ctx, newGrid, opts)
+	if err != nil {
+		return nil, err
+	}
+	d := &Diff{Removed: []ShapeDiff{}, Added: []ShapeDiff{}, Changed: []ShapeDiff{}, Cells: []CellDiff{}, old: o, new: n, grid: newGrid}
+
+	oldShapes, newShapes := o.Result().Shapes, n.Result().Shapes
+	inOld, inNew := make(map[string]int), make(map[string]int)
+	for i, k := range o.Keys() {
+		inOld[k] = i
+	}
+	for i, k := range n.Keys() {
+		inNew[k] = i
+	}
+	for i, k := range o.Keys() {
+		if _, ok := inNew[k]; !ok {
+			d.Removed = append(d.Removed, ShapeDiff{Index: i, Shape: oldShapes[i], Old: oldShapes[i].Count})
+		}
+	}
+	for i, k := range n.Keys() {
+		j, ok := inOld[k]
+		switch {
+		case !ok:
+			d.Added = append(d.Added, ShapeDiff{Index: i, Shape: newShapes[i], New: newShapes[i].Count})
+		case oldShapes[j].Count != newShapes[i].Count:
+			d.Changed = append(d.Changed, ShapeDiff{Index: i, Shape: newShapes[i], Old: oldShapes[j].Count, New: newShapes[i].Count})
+		}
+	}
+
+	d.Rows, d.Cols = len(oldGrid), len(oldGrid[0])
+	if len(newGrid) > d.Rows {
+		d.Rows = len(newGrid)
+	}
+	if len(newGrid[0]) > d.Cols {
+		d.Cols = len(newGrid[0])
+	}
+	for y := 0; y < d.Rows; y++ {
+		for x := 0; x < d.Cols; x++ {
+			ov, nv := cellAt(oldGrid, x, y, opts.Background), cellAt(newGrid, x, y, opts.Background)
+			if ov != nv {
+				d.Cells = append(d.Cells, CellDiff{X: x, Y: y, Old: ov, New: nv})
+			}
+		}
+	}
+	return d, nil
+}
+
+// cellAt returns the value of a cell, or the background beyond the edges of the grid.
+func cellAt(g [][]int, x, y, background int) int {
+	if y >= len(g) || x >= len(g[y]) {
+		return background
+	}
+	return g[y][x]
+}
+
+// Markers of the cells drawn by Print.
+const (
+	markEmpty     = '.'
+	markSet       = '#'
+	markAdded     = '+'
+	markRemoved   = '-'
+	markRelabeled = '~'
+)
+
+// Print writes a line for each shape removed, added or changed, each followed by a drawing of the shape, and then
+// the grid with its cells marked: # for a cell set in both grids and . for one empty in both, + for a cell set
+// only in the new grid, - for one set only in the old grid and ~ for one set in both with a different label.
+func (d *Diff) Print(w io.Writer) {
+	draw := func(s *state, i int) {
+		width := s.draw(w, s.shapes[i])
+		fmt.Fprintln(w, strings.Repeat("-", width+len(leftPadding)))
+	}
+	for _, sd := range d.Removed {
+		fmt.Fprintf(w, "- shape %d of old, %d times\n", sd.Index+1, sd.Old)
+		draw(d.old, sd.Index)
+	}
+	// Added and changed shapes are listed together in the order of the new grid.
+	added, changed := d.Added, d.Changed
+	for len(added) > 0 || len(changed) > 0 {
+		if len(changed) == 0 || len(added) > 0 && added[0].Index < changed[0].Index {
+			fmt.Fprintf(w, "+ shape %d of new, %d times\n", added[0].Index+1, added[0].New)
+			draw(d.new, added[0].Index)
+			added = added[1:]
+			continue
+		}
+		fmt.Fprintf(w, "~ shape %d of new, %d times, was %d\n", changed[0].Index+1, changed[0].New, changed[0].Old)
+		draw(d.new, changed[0].Index)
+		changed = changed[1:]
+	}
+	if len(d.Removed)+len(d.Added)+len(d.Changed) == 0 {
+		fmt.Fprintln(w, "no shapes removed, added or changed")
+	}
+
+	var setCells, cleared, relabeled int
+	marks := make([][]rune, d.Rows)
+	for y := range marks {
+		marks[y] = make([]rune, d.Cols)
+		for x := range marks[y] {
+			marks[y][x] = markEmpty
+			if cellAt(d.grid, x, y, d.new.opts.Background) != d.new.opts.Background {
+				marks[y][x] = markSet
+			}
+		}
+	}
+	for _, c := range d.Cells {
+		switch bg := d.new.opts.Background; {
+		case c.Old == bg:
+			marks[c.Y][c.X] = markAdded
+			setCells++
+		case c.New == bg:
+			marks[c.Y][c.X] = markRemoved
+			cleared++
+		default:
+			marks[c.Y][c.X] = markRelabeled
+			relabeled++
+		}
+	}
+	fmt.Fprintf(w, "\n%d cells set, %d cleared, %d relabeled\n", setCells, cleared, relabeled)
+	for _, row := range marks {
+		fmt.Fprintf(w, "%s%s\n", leftPadding, string(row))
+	}
+}
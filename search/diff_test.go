@@ -0,0 +1,132 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	tt := []struct {
+		old, new    [][]int
+		opts        Options
+		wantRemoved []int
+		wantAdded   []int
+		wantChanged [][3]int
+		wantCells   []CellDiff
+	}{
+		{
+			// nothing changed
+			old:  [][]int{{1, 0}, {0, 0}},
+			new:  [][]int{{1, 0}, {0, 0}},
+			opts: Options{Topology: Plane},
+		},
+		{
+			// a cell that moved is the same shape
+			old:       [][]int{{1, 0}, {0, 0}},
+			new:       [][]int{{0, 0}, {0, 1}},
+			opts:      Options{Topology: Plane},
+			wantCells: []CellDiff{{X: 0, Y: 0, Old: 1, New: 0}, {X: 1, Y: 1, Old: 0, New: 1}},
+		},
+		{
+			// the domino grew into a corner, and a second cell appeared
+			old:         [][]int{{1, 1, 0}, {0, 0, 0}, {0, 0, 0}},
+			new:         [][]int{{1, 1, 0}, {0, 1, 0}, {0, 0, 1}},
+			opts:        Options{Topology: Plane},
+			wantRemoved: []int{0},
+			wantAdded:   []int{0, 1},
+			wantCells:   []CellDiff{{X: 1, Y: 1, Old: 0, New: 1}, {X: 2, Y: 2, Old: 0, New: 1}},
+		},
+		{
+			// a second domino, turned, only counts as the same shape under rotation
+			old:         [][]int{{1, 1, 0}, {0, 0, 0}},
+			new:         [][]int{{1, 1, 0}, {0, 0, 0}, {0, 0, 1}, {0, 0, 1}},
+			opts:        Options{Topology: Plane, Equivalence: Rotation},
+			wantChanged: [][3]int{{0, 1, 2}},
+			wantCells:   []CellDiff{{X: 2, Y: 2, Old: 0, New: 1}, {X: 2, Y: 3, Old: 0, New: 1}},
+		},
+		{
+			// a relabeled cell is a different shape unless labels are ignored
+			old:         [][]int{{1, 0}},
+			new:         [][]int{{2, 0}},
+			opts:        Options{Topology: Plane},
+			wantRemoved: []int{0},
+			wantAdded:   []int{0},
+			wantCells:   []CellDiff{{X: 0, Y: 0, Old: 1, New: 2}},
+		},
+		{
+			old:       [][]int{{1, 0}},
+			new:       [][]int{{2, 0}},
+			opts:      Options{Topology: Plane, AcrossValues: true},
+			wantCells: []CellDiff{{X: 0, Y: 0, Old: 1, New: 2}},
+		},
+	}
+	for i, tc := range tt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			d, err := Compare(context.Background(), tc.old, tc.new, tc.opts)
+			if err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			indexes := func(sds []ShapeDiff) []int {
+				var is []int
+				for _, sd := range sds {
+					is = append(is, sd.Index)
+				}
+				return is
+			}
+			if got := indexes(d.Removed); !reflect.DeepEqual(got, tc.wantRemoved) {
+				t.Fatalf("want removed %v got %v", tc.wantRemoved, got)
+			}
+			if got := indexes(d.Added); !reflect.DeepEqual(got, tc.wantAdded) {
+				t.Fatalf("want added %v got %v", tc.wantAdded, got)
+			}
+			var changed [][3]int
+			for _, sd := range d.Changed {
+				changed = append(changed, [3]int{sd.Index, sd.Old, sd.New})
+			}
+			if !reflect.DeepEqual(changed, tc.wantChanged) {
+				t.Fatalf("want changed %v got %v", tc.wantChanged, changed)
+			}
+			if len(d.Cells) != 0 || len(tc.wantCells) != 0 {
+				if !reflect.DeepEqual(d.Cells, tc.wantCells) {
+					t.Fatalf("want cells %v got %v", tc.wantCells, d.Cells)
+				}
+			}
+		})
+	}
+}
+
+func TestPrintDiff(t *testing.T) {
+	tt := []struct {
+		old, new [][]int
+		want     string
+	}{
+		{
+			old:  [][]int{{1, 0}},
+			new:  [][]int{{1, 0}},
+			want: "no shapes removed, added or changed\n\n0 cells set, 0 cleared, 0 relabeled\n    #.\n",
+		},
+		{
+			// grids of different sizes are compared as if the smaller had empty cells beyond its edges
+			old: [][]int{{1, 0}},
+			new: [][]int{{0, 2, 0}, {0, 0, 1}},
+			want: "+ shape 1 of new, 1 times\n    X\n-----\n" +
+				"\n2 cells set, 1 cleared, 0 relabeled\n    -+.\n    ..+\n",
+		},
+	}
+	for i, tc := range tt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			d, err := Compare(context.Background(), tc.old, tc.new, Options{Topology: Plane})
+			if err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			var b bytes.Buffer
+			d.Print(&b)
+			if b.String() != tc.want {
+				t.Fatalf("want\n%q\ngot\n%q", tc.want, b.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package search
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const errorCondition = stateError("filter conditions are cells, width, height or holes compared to a number with =, !=, <, <=, > or >=, or edge or wraps, either with ! before it")
+const errorFilterEdge = stateError("an unbounded plane has no edges to filter on")
+
+// Property is a measure of a shape that a filter compares.
+type Property int
+
+const (
+	// Cells is the number of cells of the shape.
+	Cells Property = iota
+	// Width and Height are the size of the box around the shape, measured as Result measures them.
+	Width
+	Height
+	// Holes is the number of holes in the shape.
+	Holes
+	// Edge is 1 for a shape with a cell in the first or last row or column of the grid and 0 otherwise.
+	Edge
+	// Wraps is 1 for a shape on a torus that reaches across an edge of the grid to the opposite edge and 0
+	// otherwise.
+	Wraps
+)
+
+var properties = []string{"cells", "width", "height", "holes", "edge", "wraps"}
+
+func (p Property) String() string { return properties[p] }
+
+// Comparison is how a condition compares a property to its value.
+type Comparison int
+
+// Comparisons, written =, !=, <, <=, > and >=.
+const (
+	Equal Comparison = iota
+	NotEqual
+	Less
+	LessOrEqual
+	Greater
+	GreaterOrEqual
+)
+
+// comparisons are the comparisons as written. Each follows the shorter one it starts with, so they are matched from
+// the end.
+var comparisons = []string{"=", "!=", "<", "<=", ">", ">="}
+
+func (c Comparison) String() string { return comparisons[c] }
+
+// Condition holds for a shape when its property compares as given to the value.
+type Condition struct {
+	Property   Property
+	Comparison Comparison
+	Value      int
+}
+
+func (c Condition) String() string {
+	switch {
+	case c.Property < Edge:
+	case c.Comparison == NotEqual && c.Value == 0:
+		return c.Property.String()
+	case c.Comparison == Equal && c.Value == 0:
+		return "!" + c.Property.String()
+	}
+	return c.Property.String() + c.Comparison.String() + strconv.Itoa(c.Value)
+}
+
+// holds reports whether the condition holds for a property's value.
+func (c Condition) holds(v int) bool {
+	switch c.Comparison {
+	case NotEqual:
+		return v != c.Value
+	case Less:
+		return v < c.Value
+	case LessOrEqual:
+		return v <= c.Value
+	case Greater:
+		return v > c.Value
+	case GreaterOrEqual:
+		return v >= c.Value
+	}
+	return v == c.Value
+}
+
+// parseCondition reads a condition written as a property, a comparison and a number, as cells>=4, or as edge or
+// wraps alone, which hold when the property is 1, or with ! before them, which hold when it is 0.
+func parseCondition(s string) (Condition, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "!") {
+		i, err := parseOption(s[1:], properties)
+		if err != nil || Property(i) < Edge {
+			return Condition{}, fmt.Errorf("%s, found %q", errorCondition, s)
+		}
+		return Condition{Property: Property(i), Comparison: Equal}, nil
+	}
+	end := strings.IndexAny(s, "=!<>")
+	if end < 0 {
+		i, err := parseOption(s, properties)
+		if err != nil || Property(i) < Edge {
+			return Condition{}, fmt.Errorf("%s, found %q", errorCondition, s)
+		}
+		return Condition{Property: Property(i), Comparison: NotEqual}, nil
+	}
+	i, err := parseOption(strings.TrimSpace(s[:end]), properties)
+	if err != nil {
+		return Condition{}, fmt.Errorf("%s, found %q", errorCondition, s)
+	}
+	c := Condition{Property: Property(i)}
+	rest := s[end:]
+	for j := len(comparisons) - 1; j >= 0; j-- {
+		if strings.HasPrefix(rest, comparisons[j]) {
+			c.Comparison, rest = Comparison(j), rest[len(comparisons[j]):]
+			break
+		}
+	}
+	if c.Value, err = strconv.Atoi(strings.TrimSpace(rest)); err != nil {
+		return Condition{}, fmt.Errorf("%s, found %q", errorCondition, s)
+	}
+	return c, nil
+}
+
+// Filter keeps the shapes for which every one of its conditions holds. It is checked against each shape found
+// before duplicates are counted, so the shapes that fail it are left out of the unique shapes, their counts and
+// every list of occurrences, such as Labels, contours and polygons.
+type Filter []Condition
+
+func (f Filter) String() string {
+	cs := make([]string, len(f))
+	for i, c := range f {
+		cs[i] = c.String()
+	}
+	return strings.Join(cs, ",")
+}
+
+// UnmarshalText accepts conditions separated by commas, as cells>=4,cells<=10,holes=0,!edge.
+func (f *Filter) UnmarshalText(b []byte) error {
+	*f = nil
+	if len(b) == 0 {
+		return nil
+	}
+	for _, s := range strings.Split(string(b), ",") {
+		c, err := parseCondition(s)
+		if err != nil {
+			return err
+		}
+		*f = append(*f, c)
+	}
+	return nil
+}
+
+// uses reports whether any condition compares the property.
+func (f Filter) uses(p Property) bool {
+	for _, c := range f {
+		if c.Property == p {
+			return true
+		}
+	}
+	return false
+}
+
+// keep reports whether a shape found on the grid passes the filter of the options.
+func (s state) keep(shp shape) bool {
+	if len(s.opts.Filter) == 0 {
+		return true
+	}
+	_, width, height := s.cells(shp)
+	edge, wraps := s.edges(shp)
+	values := []int{len(shp.points), width, height, len(shp.holes), edge, wraps}
+	for _, c := range s.opts.Filter {
+		if !c.holds(values[c.Property]) {
+			return false
+		}
+	}
+	return true
+}
+
+// edges returns 1 for edge when the shape has a cell in the first or last row or column of the grid, and 1 for
+// wraps when two of its cells are neighbors across an edge of a torus.
+func (s state) edges(shp shape) (edge, wraps int) {
+	in := make(map[point]bool, len(shp.points))
+	for _, p := range shp.points {
+		in[p.transform(s.rows, s.cols)] = true
+	}
+	for t := range in {
+		if t.x == 0 || t.y == 0 || t.x == s.cols-1 || t.y == s.rows-1 {
+			edge = 1
+		}
+		for _, n := range s.neighbors(t) {
+			if (n.x < 0 || n.y < 0 || n.x >= s.cols || n.y >= s.rows) && in[n.transform(s.rows, s.cols)] {
+				wraps = 1
+			}
+		}
+	}
+	return edge, wraps
+}
@@ -0,0 +1,115 @@
+package search
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestFilter(t *testing.T) {
+	rings := [][]int{
+		{1, 1, 1, 0, 0, 0},
+		{1, 0, 1, 0, 1, 0},
+		{1, 1, 1, 0, 0, 0},
+		{0, 0, 0, 0, 0, 0},
+		{0, 0, 0, 1, 1, 0},
+	}
+	wrapping := [][]int{
+		{1, 0, 0, 1},
+		{0, 0, 0, 0},
+		{0, 1, 0, 0},
+	}
+	tt := []struct {
+		filter string
+		grid   [][]int
+		opts   Options
+		// want is the number of cells and the count of each unique shape.
+		want           [][2]int
+		wantComponents int
+	}{
+		{filter: "cells>=2", grid: rings, opts: Options{Topology: Plane}, want: [][2]int{{8, 1}, {2, 1}}, wantComponents: 2},
+		{filter: "cells<2", grid: rings, opts: Options{Topology: Plane}, want: [][2]int{{1, 1}}, wantComponents: 1},
+		{filter: "holes=1", grid: rings, opts: Options{Topology: Plane}, want: [][2]int{{8, 1}}, wantComponents: 1},
+		{filter: "!edge", grid: rings, opts: Options{Topology: Plane}, want: [][2]int{{1, 1}}, wantComponents: 1},
+		{filter: "edge,width=2", grid: rings, opts: Options{Topology: Plane}, want: [][2]int{{2, 1}}, wantComponents: 1},
+		{filter: "height>1,width<=3", grid: rings, opts: Options{Topology: Plane}, want: [][2]int{{8, 1}}, wantComponents: 1},
+		{filter: "cells!=8", grid: rings, opts: Options{Topology: Plane}, want: [][2]int{{1, 1}, {2, 1}}, wantComponents: 2},
+		{filter: "cells>8", grid: rings, opts: Options{Topology: Plane}, want: [][2]int{}},
+		{filter: "wraps", grid: wrapping, want: [][2]int{{2, 1}}, wantComponents: 1},
+		{filter: "!wraps", grid: wrapping, want: [][2]int{{1, 1}}, wantComponents: 1},
+		{filter: "wraps", grid: wrapping, opts: Options{Topology: Plane}, want: [][2]int{}},
+		{
+			// occurrences of the same shape are kept or left out one by one
+			filter:         "!edge",
+			grid:           [][]int{{0, 0, 0, 0, 0}, {0, 1, 0, 1, 0}, {0, 0, 0, 0, 0}, {1, 0, 0, 0, 0}},
+			opts:           Options{Topology: Plane},
+			want:           [][2]int{{1, 2}},
+			wantComponents: 2,
+		},
+	}
+	for i, tc := range tt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			if err := tc.opts.Filter.UnmarshalText([]byte(tc.filter)); err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			if tc.opts.Filter.String() != tc.filter {
+				t.Fatalf("want %q got %q", tc.filter, tc.opts.Filter.String())
+			}
+			s, err := NewWithOptions(tc.grid, tc.opts)
+			if err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			r := s.Result()
+			got := [][2]int{}
+			for _, shp := range r.Shapes {
+				got = append(got, [2]int{len(shp.Cells), shp.Count})
+			}
+			if !reflect.DeepEqual(got, tc.want) || r.Components != tc.wantComponents {
+				t.Fatalf("want %v in %d got %v in %d", tc.want, tc.wantComponents, got, r.Components)
+			}
+		})
+	}
+}
+
+func TestFilterErrors(t *testing.T) {
+	for i, filter := range []string{"size>3", "cells", "!cells", "!edge=1", "cells=x", "cells==3", "edge~1"} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var f Filter
+			if err := f.UnmarshalText([]byte(filter)); err == nil || !strings.HasPrefix(err.Error(), errorCondition.Error()) {
+				t.Fatalf("want error %q got %v", errorCondition, err)
+			}
+		})
+	}
+}
+
+func TestFilterOccurrences(t *testing.T) {
+	grid := [][]int{
+		{1, 0, 0},
+		{0, 0, 0},
+		{0, 1, 1},
+	}
+	opts := Options{Topology: Plane, Filter: Filter{{Property: Cells, Comparison: Greater, Value: 1}}}
+	s, err := NewWithOptions(grid, opts)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	want := [][]int{{0, 0, 0}, {0, 0, 0}, {0, 1, 1}}
+	if got := s.Labels(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("want labels %v got %v", want, got)
+	}
+
+	st, err := NewStream(context.Background(), strings.NewReader("1 0 0\n0 0 0\n0 1 1\n"), opts)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if r := st.Result(); r.Components != 1 || len(r.Shapes[0].Cells) != 2 {
+		t.Fatalf("want only the domino streamed, got %+v", r)
+	}
+
+	opts.Filter = Filter{{Property: Edge, Comparison: Equal}}
+	if _, err := NewUnbounded(context.Background(), [][2]int{{0, 0}}, opts); err != errorFilterEdge {
+		t.Fatalf("want error %v got %v", errorFilterEdge, err)
+	}
+}
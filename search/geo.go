@@ -0,0 +1,216 @@
+package search
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const errorSquareOnly = stateError("polygons are only traced on square lattices")
+
+// Affine maps grid coordinates to world coordinates in the order of a GDAL geotransform:
+//
+//	X = A[0] + col*A[1] + row*A[2]
+//	Y = A[3] + col*A[4] + row*A[5]
+//
+// Grid coordinates are cell corners, so the cell in row r and column c spans (c, r) to (c+1, r+1).
+type Affine [6]float64
+
+// Identity leaves grid coordinates unchanged.
+var Identity = Affine{0, 1, 0, 0, 0, 1}
+
+func (a Affine) apply(x, y float64) (float64, float64) {
+	return a[0] + x*a[1] + y*a[2], a[3] + x*a[4] + y*a[5]
+}
+
+// polygon is a component's outline in cell corner coordinates followed by the outlines of its holes.
+type polygon [][]point
+
+// polygon traces the outline of the shape along cell edges, with the gaps it encloses as holes. A shape that crosses
+// the edge of the grid is outlined where it continues past the edge, so its corners can fall outside the grid. A
+// shape that wraps all the way around is outlined as placed puts it on the grid.
+func (s shape) polygon() polygon {
+	cells := make(map[point]bool)
+	for _, p := range s.points {
+		cells[p] = true
+	}
+
+	// Each cell contributes the sides it does not share with another cell of the shape, running clockwise around
+	// the cell as drawn with rows going down.
+	out := make(map[point][]point)
+	for _, p := range s.points {
+		corners := []point{{p.x, p.y}, {p.x + 1, p.y}, {p.x + 1, p.y + 1}, {p.x, p.y + 1}}
+		for i, dir := range []direction{up, right, down, left} {
+			if !cells[nextPoint(p, dir)] {
+				from, to := corners[i], corners[(i+1)%4]
+				out[from] = append(out[from], to)
+			}
+		}
+	}
+
+	var outer []point
+	var holes [][]point
+	o := origin(s.points)
+	for _, p := range normalize(s.points) {
+		// Every ring runs along the top side of some cell, so scanning the cells down the rows finds each ring
+		// starting from the same corner every time.
+		from := point{p.x + o.x, p.y + o.y}
+		to := point{from.x + 1, from.y}
+		if !takeEdge(out, from, to) {
+			continue
+		}
+		ring := followRing(out, from, to)
+		if area(ring) > 0 {
+			outer = ring
+		} else {
+			holes = append(holes, ring)
+		}
+	}
+	return append(polygon{outer}, holes...)
+}
+
+// takeEdge removes the edge from the set of unused edges and reports whether it was there.
+func takeEdge(out map[point][]point, from, to point) bool {
+	for i, t := range out[from] {
+		if t.match(to) {
+			out[from] = append(out[from][:i], out[from][i+1:]...)
+			if len(out[from]) == 0 {
+				delete(out, from)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// followRing walks unused edges from the end of the edge from-to until it returns to from, keeping only the corners
+// where the ring turns. Where two cells of the shape meet only at a corner the ring turns left, so that a gap closed
+// off there becomes a hole touching the outline at that corner rather than a pinch in the outline itself.
+func followRing(out map[point][]point, from, to point) []point {
+	ring := []point{from}
+	prev, at := from, to
+	for !at.match(from) {
+		dx, dy := at.x-prev.x, at.y-prev.y
+		next := out[at][0]
+		for _, t := range out[at] {
+			if t.x-at.x == dy && t.y-at.y == -dx {
+				next = t
+			}
+		}
+		takeEdge(out, at, next)
+		if next.x-at.x != dx || next.y-at.y != dy {
+			ring = append(ring, at)
+		}
+		prev, at = at, next
+	}
+	return ring
+}
+
+// area returns the signed area of a ring by the shoelace formula.
+func area(ring []point) int {
+	var sum int
+	for i, p := range ring {
+		q := ring[(i+1)%len(ring)]
+		sum += p.x*q.y - q.x*p.y
+	}
+	return sum / 2
+}
+
+// world applies the transform to each ring and closes it, running the outline counter clockwise and the holes
+// clockwise as GeoJSON expects.
+func (pg polygon) world(a Affine) [][][2]float64 {
+	var rings [][][2]float64
+	for i, ring := range pg {
+		var r [][2]float64
+		for _, p := range append(ring, ring[0]) {
+			x, y := a.apply(float64(p.x), float64(p.y))
+			r = append(r, [2]float64{x, y})
+		}
+		if ccw := worldArea(r) > 0; ccw != (i == 0) {
+			for j, k := 0, len(r)-1; j < k; j, k = j+1, k-1 {
+				r[j], r[k] = r[k], r[j]
+			}
+		}
+		rings = append(rings, r)
+	}
+	return rings
+}
+
+func worldArea(r [][2]float64) float64 {
+	var sum float64
+	for i := 0; i+1 < len(r); i++ {
+		sum += r[i][0]*r[i+1][1] - r[i+1][0]*r[i][1]
+	}
+	return sum / 2
+}
+
+type geoFeatureCollection struct {
+	Type     string       `json:"type"`
+	Features []geoFeature `json:"features"`
+}
+
+type geoFeature struct {
+	Type       string         `json:"type"`
+	Geometry   geoGeometry    `json:"geometry"`
+	Properties map[string]int `json:"properties"`
+}
+
+type geoGeometry struct {
+	Type        string         `json:"type"`
+	Coordinates [][][2]float64 `json:"coordinates"`
+}
+
+// WriteGeoJSON writes every component as a polygon feature of a GeoJSON feature collection. Each feature's
+// properties hold the index of its unique shape, its label and its cell count.
+func (s state) WriteGeoJSON(w io.Writer, a Affine) error {
+	if s.opts.Lattice != Square {
+		return errorSquareOnly
+	}
+	a = s.shift(a)
+	fc := geoFeatureCollection{Type: "FeatureCollection", Features: []geoFeature{}}
+	for _, c := range s.components {
+		fc.Features = append(fc.Features, geoFeature{
+			Type: "Feature",
+			Geometry: geoGeometry{
+				Type:        "Polygon",
+				Coordinates: shape{points: s.placed(c)}.polygon().world(a),
+			},
+			Properties: map[string]int{
+				"shape": s.index[s.key(c)],
+				"value": c.value,
+				"cells": len(c.points),
+			},
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(fc)
+}
+
+// WriteWKT writes every component as a well known text polygon, one per line.
+func (s state) WriteWKT(w io.Writer, a Affine) error {
+	if s.opts.Lattice != Square {
+		return errorSquareOnly
+	}
+	a = s.shift(a)
+	for _, c := range s.components {
+		var rings []string
+		for _, r := range (shape{points: s.placed(c)}).polygon().world(a) {
+			var coords []string
+			for _, p := range r {
+				coords = append(coords, formatFloat(p[0])+" "+formatFloat(p[1]))
+			}
+			rings = append(rings, "("+strings.Join(coords, ", ")+")")
+		}
+		if _, err := fmt.Fprintf(w, "POLYGON (%s)\n", strings.Join(rings, ", ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,186 @@
+package search
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"testing"
+)
+
+func TestPolygon(t *testing.T) {
+	tt := []struct {
+		grid [][]int
+		want polygon
+	}{
+		{
+			grid: [][]int{
+				{0, 0, 0},
+				{0, 1, 0},
+				{0, 0, 0},
+			},
+			want: polygon{{{1, 1}, {2, 1}, {2, 2}, {1, 2}}},
+		},
+		{
+			grid: [][]int{
+				{0, 0, 0, 0, 0},
+				{0, 1, 1, 1, 0},
+				{0, 1, 0, 1, 0},
+				{0, 1, 1, 1, 0},
+				{0, 0, 0, 0, 0},
+			},
+			want: polygon{
+				{{1, 1}, {4, 1}, {4, 4}, {1, 4}},
+				{{2, 3}, {3, 3}, {3, 2}, {2, 2}},
+			},
+		},
+		{
+			// cells touching at a corner stay apart
+			grid: [][]int{
+				{0, 0, 0, 0, 0},
+				{0, 1, 1, 1, 0},
+				{0, 1, 0, 1, 0},
+				{0, 1, 1, 0, 0},
+				{0, 0, 0, 0, 0},
+			},
+			want: polygon{
+				{{1, 1}, {4, 1}, {4, 3}, {3, 3}, {3, 4}, {1, 4}},
+				{{2, 3}, {3, 3}, {3, 2}, {2, 2}},
+			},
+		},
+		{
+			// the shape continues past the right edge of the grid
+			grid: [][]int{
+				{0, 0, 0},
+				{1, 0, 1},
+				{0, 0, 0},
+			},
+			want: polygon{{{-1, 1}, {1, 1}, {1, 2}, {-1, 2}}},
+		},
+	}
+
+	for i, tc := range tt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			s, err := New(tc.grid)
+			if err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			got := s.components[0].polygon()
+			if len(got) != len(tc.want) {
+				t.Fatalf("want %v got %v", tc.want, got)
+			}
+			for j := range got {
+				if len(got[j]) != len(tc.want[j]) {
+					t.Fatalf("want %v got %v", tc.want, got)
+				}
+				for k := range got[j] {
+					if !got[j][k].match(tc.want[j][k]) {
+						t.Fatalf("want %v got %v", tc.want, got)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestWriteWKT(t *testing.T) {
+	s, err := New([][]int{
+		{0, 0, 0, 0, 0},
+		{0, 1, 1, 1, 0},
+		{0, 1, 0, 1, 0},
+		{0, 1, 1, 1, 0},
+		{0, 0, 0, 0, 0},
+	})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	tt := []struct {
+		affine Affine
+		want   string
+	}{
+		{
+			affine: Identity,
+			want:   "POLYGON ((1 1, 4 1, 4 4, 1 4, 1 1), (2 3, 3 3, 3 2, 2 2, 2 3))\n",
+		},
+		{
+			// ten units a cell with rows running south from y=100
+			affine: Affine{0, 10, 0, 100, 0, -10},
+			want:   "POLYGON ((10 90, 10 60, 40 60, 40 90, 10 90), (20 70, 20 80, 30 80, 30 70, 20 70))\n",
+		},
+	}
+	for i, tc := range tt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var w bytes.Buffer
+			if err := s.WriteWKT(&w, tc.affine); err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			if w.String() != tc.want {
+				t.Logf("want %q", tc.want)
+				t.Logf("got  %q", w.String())
+				t.Fatal()
+			}
+		})
+	}
+}
+
+func TestWriteWKTAround(t *testing.T) {
+	tt := []struct {
+		grid [][]int
+		want string
+	}{
+		// a shape wrapping all the way around is outlined on the grid, not where the search unrolled it
+		{grid: [][]int{{1, 1}, {1, 1}}, want: "POLYGON ((0 0, 2 0, 2 2, 0 2, 0 0))\n"},
+		{grid: [][]int{{0, 0, 0}, {1, 1, 1}, {0, 0, 0}}, want: "POLYGON ((0 1, 3 1, 3 2, 0 2, 0 1))\n"},
+	}
+	for i, tc := range tt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			s, err := New(tc.grid)
+			if err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			var w bytes.Buffer
+			if err := s.WriteWKT(&w, Identity); err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			if w.String() != tc.want {
+				t.Fatalf("want %q got %q", tc.want, w.String())
+			}
+		})
+	}
+}
+
+func TestWriteGeoJSON(t *testing.T) {
+	s, err := New([][]int{
+		{1, 0, 0, 0},
+		{0, 0, 1, 0},
+		{0, 0, 0, 0},
+	})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	var w bytes.Buffer
+	if err := s.WriteGeoJSON(&w, Identity); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	var got geoFeatureCollection
+	if err := json.Unmarshal(w.Bytes(), &got); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if got.Type != "FeatureCollection" || len(got.Features) != 2 {
+		t.Fatalf("unexpected collection %+v", got)
+	}
+	f := got.Features[1]
+	if f.Geometry.Type != "Polygon" || f.Properties["shape"] != 0 || f.Properties["cells"] != 1 {
+		t.Fatalf("unexpected feature %+v", f)
+	}
+	want := [][2]float64{{2, 1}, {3, 1}, {3, 2}, {2, 2}, {2, 1}}
+	ring := f.Geometry.Coordinates[0]
+	if len(ring) != len(want) {
+		t.Fatalf("want %v got %v", want, ring)
+	}
+	for i := range ring {
+		if ring[i] != want[i] {
+			t.Fatalf("want %v got %v", want, ring)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package search
+
+import "sort"
+
+const errorOffGrid = stateError("cell is off the grid")
+
+// Grid is a board of labeled cells searched for shapes. Grids that only store their set cells treat every other
+// cell as holding zero, so they are searched with the default background.
+type Grid interface {
+	// Rows returns the number of rows in the grid.
+	Rows() int
+	// Cols returns the number of columns in the grid.
+	Cols() int
+	// Value returns the label of the cell in column x and row y.
+	Value(x, y int) int
+	// Next returns the first cell at or after column x of row y, in row order, that may be part of a shape. Grids
+	// that know where their set cells are skip the empty ones, others return the next cell.
+	Next(x, y int) (nx, ny int, ok bool)
+}
+
+// Dense is a grid holding a label for every cell, one slice per row.
+type Dense [][]int
+
+// Rows returns the number of rows in the grid.
+func (d Dense) Rows() int { return len(d) }
+
+// Cols returns the number of columns in the grid.
+func (d Dense) Cols() int {
+	if len(d) == 0 {
+		return 0
+	}
+	return len(d[0])
+}
+
+// Value returns the label of the cell in column x and row y.
+func (d Dense) Value(x, y int) int { return d[y][x] }
+
+// Next returns the cell at column x of row y, moving to the start of the next row when x is past the end.
+func (d Dense) Next(x, y int) (int, int, bool) {
+	if x >= d.Cols() {
+		x, y = 0, y+1
+	}
+	return x, y, y < d.Rows()
+}
+
+// check reports rows that are missing or differ in length.
+func (d Dense) check() error {
+	if len(d) == 0 {
+		return errorNoRows
+	}
+	if len(d[0]) == 0 {
+		return errorNoCols
+	}
+	for _, row := range d {
+		if len(row) != len(d[0]) {
+			return errorRagged
+		}
+	}
+	return nil
+}
+
+// Sparse is a grid that only stores the cells holding a label other than zero. It suits huge boards with few set
+// cells, which would not fit in memory with a label for every cell.
+type Sparse struct {
+	rows, cols int
+	cells      map[point]int
+	// order holds the set cells in row order. It is rebuilt when it is needed after a cell changes.
+	order []point
+}
+
+// NewSparse returns an empty grid of the given size.
+func NewSparse(rows, cols int) *Sparse {
+	return &Sparse{rows: rows, cols: cols, cells: make(map[point]int)}
+}
+
+// Rows returns the number of rows in the grid.
+func (s *Sparse) Rows() int { return s.rows }
+
+// Cols returns the number of columns in the grid.
+func (s *Sparse) Cols() int { return s.cols }
+
+// Len returns the number of set cells.
+func (s *Sparse) Len() int { return len(s.cells) }
+
+// Value returns the label of the cell in column x and row y.
+func (s *Sparse) Value(x, y int) int { return s.cells[point{x, y}] }
+
+// Set labels the cell in column x and row y, clearing it when v is zero. It panics if the cell is off the grid.
+func (s *Sparse) Set(x, y, v int) {
+	if x < 0 || x >= s.cols || y < 0 || y >= s.rows {
+		panic(errorOffGrid)
+	}
+	p := point{x, y}
+	if v == unset {
+		delete(s.cells, p)
+	} else {
+		s.cells[p] = v
+	}
+	s.order = nil
+}
+
+// Next returns the first set cell at or after column x of row y in row order.
+func (s *Sparse) Next(x, y int) (int, int, bool) {
+	if s.order == nil {
+		s.order = make([]point, 0, len(s.cells))
+		for p := range s.cells {
+			s.order = append(s.order, p)
+		}
+		sort.Slice(s.order, func(i, j int) bool { return before(s.order[i], s.order[j]) })
+	}
+	at := point{x, y}
+	i := sort.Search(len(s.order), func(i int) bool { return !before(s.order[i], at) })
+	if i == len(s.order) {
+		return 0, 0, false
+	}
+	return s.order[i].x, s.order[i].y, true
+}
+
+// before reports whether a comes before b in row order.
+func before(a, b point) bool {
+	if a.y != b.y {
+		return a.y < b.y
+	}
+	return a.x < b.x
+}
+
+// marks records the cells a search has visited, by their position on the grid.
+type marks interface {
+	mark(p point)
+	marked(p point) bool
+}
+
+// flags marks cells with a bool each, so cells can be marked from several goroutines at once.
+type flags [][]bool
+
+func newFlags(rows, cols int) flags {
+	f := make(flags, rows)
+	for i := range f {
+		f[i] = make([]bool, cols)
+	}
+	return f
+}
+
+func (f flags) mark(p point)        { f[p.y][p.x] = true }
+func (f flags) marked(p point) bool { return f[p.y][p.x] }
+
+// cellSet marks cells of a sparse grid, holding only those that have been marked.
+type cellSet map[point]bool
+
+func (c cellSet) mark(p point)        { c[p] = true }
+func (c cellSet) marked(p point) bool { return c[p] }
@@ -0,0 +1,145 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func sparseGrid(grid [][]int) *Sparse {
+	s := NewSparse(len(grid), len(grid[0]))
+	for y, row := range grid {
+		for x, v := range row {
+			s.Set(x, y, v)
+		}
+	}
+	return s
+}
+
+func TestNext(t *testing.T) {
+	grid := [][]int{
+		{0, 1, 0},
+		{0, 0, 0},
+		{2, 0, 3},
+	}
+	packed, err := BitsFromInts([][]int{{0, 1, 0}, {0, 0, 0}, {1, 0, 1}})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	tt := []struct {
+		grid Grid
+		want []point
+	}{
+		{Dense(grid), []point{{0, 0}, {1, 0}, {2, 0}, {0, 1}, {1, 1}, {2, 1}, {0, 2}, {1, 2}, {2, 2}}},
+		{packed, []point{{1, 0}, {0, 2}, {2, 2}}},
+		{sparseGrid(grid), []point{{1, 0}, {0, 2}, {2, 2}}},
+	}
+	for i, tc := range tt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var got []point
+			for x, y, ok := tc.grid.Next(0, 0); ok; x, y, ok = tc.grid.Next(x+1, y) {
+				got = append(got, point{x, y})
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("want %v got %v", tc.want, got)
+			}
+			for j := range tc.want {
+				if !got[j].match(tc.want[j]) {
+					t.Fatalf("want %v got %v", tc.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestSparseSet(t *testing.T) {
+	s := NewSparse(2, 2)
+	s.Set(1, 1, 4)
+	s.Set(0, 1, 2)
+	s.Set(0, 1, 0)
+	if s.Len() != 1 || s.Value(1, 1) != 4 || s.Value(0, 1) != 0 {
+		t.Fatalf("unexpected cells %v", s.cells)
+	}
+	defer func() {
+		if r := recover(); r != errorOffGrid {
+			t.Fatalf("want panic %q got %v", errorOffGrid, r)
+		}
+	}()
+	s.Set(2, 0, 1)
+}
+
+func TestSearchSparse(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	opts := []Options{
+		{},
+		{Topology: Plane},
+		{Connectivity: EightWay},
+		{Topology: Plane, Connectivity: EightWay, Equivalence: Reflection, FillHoles: true},
+		{Topology: Plane, Lattice: Hex, Equivalence: Reflection},
+		{Topology: Plane, Lattice: Triangle, Equivalence: Rotation},
+	}
+	for i := 0; i < 40; i++ {
+		grid := randomGrid(r, 10+r.Intn(30), 10+r.Intn(30), 1+r.Intn(3), 0.1+r.Float64()*0.15)
+		for j, o := range opts {
+			t.Run(strconv.Itoa(i)+"/"+strconv.Itoa(j), func(t *testing.T) {
+				dense, err := NewWithOptions(grid, o)
+				if err != nil {
+					t.Fatal("unexpected error", err)
+				}
+				sparse, err := NewFromGrid(context.Background(), sparseGrid(grid), o)
+				if err != nil {
+					t.Fatal("unexpected error", err)
+				}
+				var want, got bytes.Buffer
+				dense.Print(&want)
+				sparse.Print(&got)
+				if want.String() != got.String() {
+					t.Fatalf("want\n%s\ngot\n%s", want.String(), got.String())
+				}
+			})
+		}
+	}
+}
+
+func TestSparseHoles(t *testing.T) {
+	ring := []point{{0, 0}, {1, 0}, {2, 0}, {0, 1}, {2, 1}, {0, 2}, {1, 2}, {2, 2}}
+	tt := []struct {
+		// at is where the ring's upper left corner is placed on a million by million grid.
+		at    point
+		inner int
+		opts  Options
+		holes int
+	}{
+		{point{5, 5}, unset, Options{}, 1},
+		{point{999999, 999999}, unset, Options{}, 1},
+		{point{999999, 999999}, unset, Options{Topology: Plane}, 0},
+		{point{5, 5}, set, Options{}, 0},
+		{point{5, 5}, unset, Options{Connectivity: EightWay}, 1},
+	}
+	for i, tc := range tt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			g := NewSparse(1000000, 1000000)
+			for _, p := range ring {
+				t := point{tc.at.x + p.x, tc.at.y + p.y}.transform(g.rows, g.cols)
+				g.Set(t.x, t.y, set)
+			}
+			if tc.inner != unset {
+				t := point{tc.at.x + 1, tc.at.y + 1}.transform(g.rows, g.cols)
+				g.Set(t.x, t.y, tc.inner)
+			}
+			s, err := NewFromGrid(context.Background(), g, tc.opts)
+			if err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			var holes int
+			for _, c := range s.components {
+				holes += len(c.holes)
+			}
+			if holes != tc.holes {
+				t.Fatalf("want %d holes got %d", tc.holes, holes)
+			}
+		})
+	}
+}
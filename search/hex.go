@@ -0,0 +1,102 @@
+package search
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+const errorHexRows = stateError("a hex grid on a torus needs an even number of rows")
+
+// hexEven and hexOdd list the offsets of the six neighbors of a hex cell in an even and an odd row. Odd rows are
+// pushed half a cell right, so the rows above and below reach one column further right than from an even row.
+var (
+	hexEven = []point{{0, -1}, {1, 0}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1}}
+	hexOdd  = []point{{1, -1}, {1, 0}, {1, 1}, {0, 1}, {-1, 0}, {0, -1}}
+)
+
+// hexNeighbors returns the six cells around p on a hex lattice.
+func hexNeighbors(p point) []point {
+	offsets := hexEven
+	if p.y&1 == 1 {
+		offsets = hexOdd
+	}
+	ns := make([]point, len(offsets))
+	for i, d := range offsets {
+		ns[i] = point{p.x + d.x, p.y + d.y}
+	}
+	return ns
+}
+
+// OffsetToAxial converts the column and row of a hex cell, with odd rows pushed half a cell right, to axial
+// coordinates, where the q axis runs along a row and the r axis down and to the right.
+func OffsetToAxial(col, row int) (q, r int) {
+	return col - (row-row&1)/2, row
+}
+
+// AxialToOffset converts axial coordinates of a hex cell to its column and row.
+func AxialToOffset(q, r int) (col, row int) {
+	return q + (r-r&1)/2, r
+}
+
+// axial converts hex cells from offset to axial coordinates.
+func axial(ps []point) []point {
+	as := make([]point, len(ps))
+	for i, p := range ps {
+		as[i].x, as[i].y = OffsetToAxial(p.x, p.y)
+	}
+	return as
+}
+
+// rotateHex turns points in axial coordinates a sixth of a turn.
+func rotateHex(ps []point) []point {
+	turned := make([]point, len(ps))
+	for i, p := range ps {
+		turned[i] = point{-p.y, p.x + p.y}
+	}
+	return turned
+}
+
+// mirrorHex flips points in axial coordinates over the line where q equals r.
+func mirrorHex(ps []point) []point {
+	flipped := make([]point, len(ps))
+	for i, p := range ps {
+		flipped[i] = point{p.y, p.x}
+	}
+	return flipped
+}
+
+// doubled places hex cells on a grid twice as wide, where each cell takes an even or odd column as its row is even
+// or odd, so staggered rows can be drawn with one character per half cell.
+func doubled(ps []point) []point {
+	ds := make([]point, len(ps))
+	for i, p := range ps {
+		ds[i] = point{2*p.x + p.y&1, p.y}
+	}
+	return normalize(ds)
+}
+
+// drawHex draws hex cells with odd rows pushed half a cell right and returns the width drawn.
+func drawHex(w io.Writer, ps []point, g Glyphs) int {
+	ds := doubled(ps)
+	var width, height int
+	for _, p := range ds {
+		if p.x+1 > width {
+			width = p.x + 1
+		}
+		if p.y+1 > height {
+			height = p.y + 1
+		}
+	}
+	lines := make([][]rune, height)
+	for i := range lines {
+		lines[i] = []rune(strings.Repeat(string(g.Unset), width))
+	}
+	for _, p := range ds {
+		lines[p.y][p.x] = g.Set
+	}
+	for _, l := range lines {
+		fmt.Fprintf(w, "%s%s\n", leftPadding, string(l))
+	}
+	return width
+}
@@ -0,0 +1,206 @@
+package search
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+)
+
+func TestHexNeighbors(t *testing.T) {
+	for y := -3; y <= 3; y++ {
+		for x := -3; x <= 3; x++ {
+			p := point{x, y}
+			for _, n := range hexNeighbors(p) {
+				var back bool
+				for _, m := range hexNeighbors(n) {
+					back = back || m.match(p)
+				}
+				if !back {
+					t.Fatalf("%v is a neighbor of %v but not the other way round", n, p)
+				}
+			}
+		}
+	}
+}
+
+func TestAxial(t *testing.T) {
+	tt := []struct {
+		col, row, q, r int
+	}{
+		{0, 0, 0, 0},
+		{3, 1, 3, 1},
+		{3, 2, 2, 2},
+		{0, -1, 1, -1},
+		{-2, -3, 0, -3},
+	}
+	for i, tc := range tt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			if q, r := OffsetToAxial(tc.col, tc.row); q != tc.q || r != tc.r {
+				t.Fatalf("want %d,%d got %d,%d", tc.q, tc.r, q, r)
+			}
+			if col, row := AxialToOffset(tc.q, tc.r); col != tc.col || row != tc.row {
+				t.Fatalf("want %d,%d got %d,%d", tc.col, tc.row, col, row)
+			}
+		})
+	}
+}
+
+// polyhexes returns every fixed polyhex of n cells in offset coordinates, as layouts grown one cell at a time.
+func polyhexes(n int) [][]point {
+	layouts := map[string][]point{"0,0;": {{0, 0}}}
+	for size := 1; size < n; size++ {
+		grown := make(map[string][]point)
+		for _, ps := range layouts {
+			for _, p := range ps {
+				for _, c := range hexNeighbors(p) {
+					next := append(append([]point(nil), ps...), c)
+					if !contains(ps, c) {
+						grown[(shape{points: axial(next)}).key()] = next
+					}
+				}
+			}
+		}
+		layouts = grown
+	}
+	var all [][]point
+	for _, ps := range layouts {
+		all = append(all, ps)
+	}
+	return all
+}
+
+func contains(ps []point, p point) bool {
+	for _, q := range ps {
+		if q.match(p) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestHexCanonical(t *testing.T) {
+	// Counts of fixed, one sided and free polyhexes of one to five cells.
+	want := map[Equivalence][]int{
+		Translation: {1, 3, 11, 44, 186},
+		Rotation:    {1, 1, 3, 10, 33},
+		Reflection:  {1, 1, 3, 7, 22},
+	}
+	for e, counts := range want {
+		for n, count := range counts {
+			t.Run(e.String()+"/"+strconv.Itoa(n+1), func(t *testing.T) {
+				keys := make(map[string]bool)
+				for _, ps := range polyhexes(n + 1) {
+					keys[(shape{points: ps}).canonical(e, Hex)] = true
+				}
+				if len(keys) != count {
+					t.Fatalf("want %d shapes got %d", count, len(keys))
+				}
+			})
+		}
+	}
+}
+
+func TestHexSearch(t *testing.T) {
+	tt := []struct {
+		grid       [][]int
+		opts       Options
+		wantShapes int
+		wantCount  int
+		wantHoles  int
+		err        error
+	}{
+		{
+			// the cells touch on a hex lattice since the odd row is pushed right
+			grid: [][]int{
+				{0, 1, 0, 0},
+				{0, 1, 0, 0},
+				{0, 0, 0, 0},
+				{0, 0, 0, 0},
+			},
+			opts:       Options{Lattice: Hex},
+			wantShapes: 1,
+			wantCount:  1,
+		},
+		{
+			// the same layout shifted down a row still matches
+			grid: [][]int{
+				{0, 1, 0, 0, 0, 0},
+				{0, 1, 0, 0, 1, 0},
+				{0, 0, 0, 0, 0, 1},
+				{0, 0, 0, 0, 0, 0},
+			},
+			opts:       Options{Lattice: Hex},
+			wantShapes: 1,
+			wantCount:  2,
+		},
+		{
+			// a ring of six cells around an empty one
+			grid: [][]int{
+				{0, 0, 0, 0, 0},
+				{0, 1, 1, 0, 0},
+				{0, 1, 0, 1, 0},
+				{0, 1, 1, 0, 0},
+				{0, 0, 0, 0, 0},
+				{0, 0, 0, 0, 0},
+			},
+			opts:       Options{Lattice: Hex},
+			wantShapes: 1,
+			wantCount:  1,
+			wantHoles:  1,
+		},
+		{
+			grid: [][]int{
+				{1, 0},
+				{0, 0},
+				{0, 1},
+			},
+			opts: Options{Lattice: Hex},
+			err:  errorHexRows,
+		},
+		{
+			grid: [][]int{
+				{1, 0},
+				{0, 0},
+				{0, 1},
+			},
+			opts:       Options{Lattice: Hex, Topology: Plane},
+			wantShapes: 1,
+			wantCount:  2,
+		},
+	}
+	for i, tc := range tt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			s, err := NewWithOptions(tc.grid, tc.opts)
+			if err != tc.err {
+				t.Fatalf("want error %v got %v", tc.err, err)
+			}
+			if err != nil {
+				return
+			}
+			if len(s.shapes) != tc.wantShapes || s.total() != tc.wantCount {
+				t.Fatalf("want %d shapes and %d components got %d and %d", tc.wantShapes, tc.wantCount, len(s.shapes), s.total())
+			}
+			if holes := len(s.shapes[0].holes); holes != tc.wantHoles {
+				t.Fatalf("want %d holes got %d", tc.wantHoles, holes)
+			}
+		})
+	}
+}
+
+func TestPrintHex(t *testing.T) {
+	s, err := NewWithOptions([][]int{
+		{0, 0, 0, 0},
+		{1, 1, 0, 0},
+		{1, 0, 0, 0},
+		{0, 0, 0, 0},
+	}, Options{Lattice: Hex})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	var b bytes.Buffer
+	s.Print(&b)
+	want := "     X X\n    X   \n--------\n"
+	if b.String() != want {
+		t.Fatalf("want\n%q\ngot\n%q", want, b.String())
+	}
+}
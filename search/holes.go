@@ -0,0 +1,217 @@
+package search
+
+import "context"
+
+// around lists the offsets of the eight cells surrounding a cell. When shapes connect only through edges, background
+// is connected through corners as well so that it cannot leak between shapes that only touch diagonally.
+var around = []point{
+	{0, -1}, {1, -1}, {1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1},
+}
+
+// beside lists the offsets of the four cells sharing an edge with a cell. When shapes connect through corners,
+// background only connects through edges.
+var beside = []point{
+	{0, -1}, {1, 0}, {0, 1}, {-1, 0},
+}
+
+// region is a connected area of background cells.
+type region struct {
+	points []point
+	// owners are the components bordering the region.
+	owners map[int]bool
+	// open is true when the region reaches past the edge of a plane, or reaches itself by going around a torus. On a
+	// torus this is what tells the space outside a shape apart from a hole inside it.
+	open bool
+	// offset moves points into the coordinates of the first bordering component.
+	offset point
+}
+
+// backgroundNeighbors returns the cells that background at p connects to, including those past the edge of a plane.
+func (s state) backgroundNeighbors(p point) []point {
+	switch s.opts.Lattice {
+	case Hex:
+		return hexNeighbors(p)
+	case Triangle:
+		return triangleCorners(p)
+	}
+	steps := around
+	if s.opts.Connectivity == EightWay {
+		steps = beside
+	}
+	ns := make([]point, len(steps))
+	for i, d := range steps {
+		ns[i] = point{p.x + d.x, p.y + d.y}
+	}
+	return ns
+}
+
+// findHoles records the background regions that are enclosed by a single component as holes of that component. If
+// ctx is done before every region has been checked no holes are recorded.
+func (s *state) findHoles(ctx context.Context) error {
+	if s.sparse() {
+		return s.findHolesNear(ctx)
+	}
+	// owner holds the component each cell belongs to, or -1 for background. lift holds the point each cell was
+	// reached at, in the coordinates of its component or its background region.
+	owner := make([][]int, s.rows)
+	lift := make([][]point, s.rows)
+	done := make([][]bool, s.rows)
+	for row := 0; row < s.rows; row++ {
+		owner[row] = make([]int, s.cols)
+		lift[row] = make([]point, s.cols)
+		done[row] = make([]bool, s.cols)
+		for col := range owner[row] {
+			owner[row][col] = -1
+		}
+	}
+	for i, c := range s.components {
+		for _, p := range c.points {
+			t := p.transform(s.rows, s.cols)
+			owner[t.y][t.x] = i
+			lift[t.y][t.x] = p
+		}
+	}
+
+	holes := make(map[int][][]point)
+	for row := 0; row < s.rows; row++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		for col := 0; col < s.cols; col++ {
+			if owner[row][col] >= 0 || done[row][col] {
+				continue
+			}
+			r := s.backgroundRegion(getPoint(col, row), owner, lift, done)
+			if r.open || len(r.owners) != 1 {
+				continue
+			}
+			for o := range r.owners {
+				hole := make([]point, len(r.points))
+				for i, p := range r.points {
+					hole[i] = point{p.x + r.offset.x, p.y + r.offset.y}
+				}
+				holes[o] = append(holes[o], hole)
+			}
+		}
+	}
+	for o, h := range holes {
+		s.components[o].holes = h
+	}
+	return nil
+}
+
+func (s state) backgroundRegion(start point, owner [][]int, lift [][]point, done [][]bool) region {
+	r := region{owners: make(map[int]bool)}
+	lift[start.y][start.x] = start
+	done[start.y][start.x] = true
+	// The queue keeps every point it has held, so it ends as the region.
+	queue := []point{start}
+	for i := 0; i < len(queue); i++ {
+		p := queue[i]
+		for _, n := range s.backgroundNeighbors(p) {
+			if len(s.onGrid([]point{n})) == 0 {
+				r.open = true
+				continue
+			}
+			t := n.transform(s.rows, s.cols)
+			if o := owner[t.y][t.x]; o >= 0 {
+				if len(r.owners) == 0 {
+					l := lift[t.y][t.x]
+					r.offset = point{l.x - n.x, l.y - n.y}
+				}
+				r.owners[o] = true
+				continue
+			}
+			if done[t.y][t.x] {
+				if !lift[t.y][t.x].match(n) {
+					r.open = true
+				}
+				continue
+			}
+			lift[t.y][t.x] = n
+			done[t.y][t.x] = true
+			queue = append(queue, n)
+		}
+	}
+	r.points = queue
+	return r
+}
+
+// filled returns the shape's points together with the points of its holes.
+func (s shape) filled() []point {
+	ps := append([]point(nil), s.points...)
+	for _, h := range s.holes {
+		ps = append(ps, h...)
+	}
+	return ps
+}
+
+// findHolesNear finds holes without keeping anything for every cell of the grid, by searching the background inside
+// each component's bounding box. A region that reaches past the box is outside the component and one that touches
+// another component is not enclosed by it alone. On a torus the box and the cells around it must fit inside the
+// grid, so components that reach all the way around it are not given holes.
+func (s *state) findHolesNear(ctx context.Context) error {
+	const (
+		empty = iota
+		own
+		other
+		done
+	)
+	holes := make([][][]point, len(s.components))
+	for i, c := range s.components {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		lx, ux, ly, uy := shapeDimensions(c.points)
+		o := point{lx, ly}
+		w, h := ux-lx+1, uy-ly+1
+		// Background reaches two columns on a triangular lattice and one everywhere else.
+		reach := 1
+		if s.opts.Lattice == Triangle {
+			reach = 2
+		}
+		if s.opts.Topology != Plane && (w+2*reach > s.cols || h+2 > s.rows) {
+			continue
+		}
+		box := make([]int, w*h)
+		for _, p := range c.points {
+			box[(p.y-o.y)*w+p.x-o.x] = own
+		}
+		at := func(p point) *int { return &box[(p.y-o.y)*w+p.x-o.x] }
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				start := point{o.x + x, o.y + y}
+				if *at(start) != empty || s.isShapePart(start) {
+					continue
+				}
+				*at(start) = done
+				enclosed := true
+				queue := []point{start}
+				for j := 0; j < len(queue); j++ {
+					for _, n := range s.backgroundNeighbors(queue[j]) {
+						if len(s.onGrid([]point{n})) == 0 || n.x < o.x || n.x >= o.x+w || n.y < o.y || n.y >= o.y+h {
+							enclosed = false
+							continue
+						}
+						switch b := at(n); {
+						case *b == own || *b == done:
+						case *b == other || s.isShapePart(n):
+							*b = other
+							enclosed = false
+						default:
+							*b = done
+							queue = append(queue, n)
+						}
+					}
+				}
+				if enclosed {
+					holes[i] = append(holes[i], queue)
+				}
+			}
+		}
+	}
+	for i, h := range holes {
+		s.components[i].holes = h
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package search
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestHoles(t *testing.T) {
+	tt := []struct {
+		grid      [][]int
+		wantHoles []int
+	}{
+		{
+			grid: [][]int{
+				{0, 0, 0, 0, 0},
+				{0, 1, 1, 1, 0},
+				{0, 1, 0, 1, 0},
+				{0, 1, 1, 1, 0},
+				{0, 0, 0, 0, 0},
+			},
+			wantHoles: []int{1},
+		},
+		{
+			// the ring crosses the left and right edges
+			grid: [][]int{
+				{0, 0, 0, 0, 0},
+				{1, 1, 0, 0, 1},
+				{0, 1, 0, 0, 1},
+				{1, 1, 0, 0, 1},
+				{0, 0, 0, 0, 0},
+			},
+			wantHoles: []int{1},
+		},
+		{
+			// a band around the grid separates background from itself but encloses nothing
+			grid: [][]int{
+				{0, 0, 0, 0},
+				{1, 1, 1, 1},
+				{0, 0, 0, 0},
+				{0, 0, 0, 0},
+			},
+			wantHoles: []int{0},
+		},
+		{
+			// the ring is open at a corner
+			grid: [][]int{
+				{0, 0, 0, 0, 0},
+				{0, 1, 1, 0, 0},
+				{0, 1, 0, 1, 0},
+				{0, 1, 1, 1, 0},
+				{0, 0, 0, 0, 0},
+			},
+			wantHoles: []int{0},
+		},
+		{
+			// two shapes enclose the region together, so it is nobody's hole
+			grid: [][]int{
+				{0, 0, 0, 0, 0, 0},
+				{0, 1, 1, 2, 2, 0},
+				{0, 1, 0, 0, 2, 0},
+				{0, 1, 1, 2, 2, 0},
+				{0, 0, 0, 0, 0, 0},
+			},
+			wantHoles: []int{0, 0},
+		},
+		{
+			grid: [][]int{
+				{0, 0, 0, 0, 0, 0, 0},
+				{0, 1, 1, 1, 1, 1, 0},
+				{0, 1, 0, 1, 0, 1, 0},
+				{0, 1, 1, 1, 1, 1, 0},
+				{0, 0, 0, 0, 0, 0, 0},
+			},
+			wantHoles: []int{2},
+		},
+	}
+
+	for i, tc := range tt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			s, err := New(tc.grid)
+			if err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			if len(s.components) != len(tc.wantHoles) {
+				t.Fatalf("want %d components got %d", len(tc.wantHoles), len(s.components))
+			}
+			for j, c := range s.components {
+				if len(c.holes) != tc.wantHoles[j] {
+					t.Fatalf("component %d want %d holes got %d", j, tc.wantHoles[j], len(c.holes))
+				}
+			}
+		})
+	}
+}
+
+func TestFillHoles(t *testing.T) {
+	grid := [][]int{
+		{0, 0, 0, 0, 0, 0, 0, 0, 0},
+		{0, 1, 1, 1, 0, 1, 1, 1, 0},
+		{0, 1, 0, 1, 0, 1, 1, 1, 0},
+		{0, 1, 1, 1, 0, 1, 1, 1, 0},
+		{0, 0, 0, 0, 0, 0, 0, 0, 0},
+	}
+	s, err := New(grid)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if len(s.shapes) != 2 {
+		t.Fatalf("want 2 shapes got %d", len(s.shapes))
+	}
+
+	s, err = NewWithOptions(grid, Options{FillHoles: true})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if len(s.shapes) != 1 {
+		t.Fatalf("want 1 shape got %d", len(s.shapes))
+	}
+	if len(s.shapes[0].holes) != 1 {
+		t.Fatalf("want the ring to be reported with its hole")
+	}
+}
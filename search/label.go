@@ -0,0 +1,86 @@
+package search
+
+import "context"
+
+// labeling is a grid of any number of axes as a search labels it: the cells joined to each cell, and which cells
+// may still be taken into a shape. Grids of two axes, on any lattice, and spaces of any number of axes are all
+// labeled by flood.
+type labeling interface {
+	// joined appends the unrolled coordinates of the cells joined to c to ns, one axis after another, and returns
+	// the extended slice.
+	joined(ns, c []int) []int
+	// take claims the cell that unrolled c lands on for a shape of the value. It reports false if the cell holds
+	// another value or was claimed already.
+	take(c []int, value int) bool
+}
+
+// flood collects the cells joined to start that hold its value, in the order a depth first search finds them. Start
+// must already be taken. Cells are kept in unrolled coordinates, so a shape that wraps across an edge continues
+// past it, and are returned one after another, dims coordinates each. If ctx is done part way through the shape is
+// abandoned.
+func flood(ctx context.Context, l labeling, dims int, start []int, value int) ([]int, error) {
+	type frame struct {
+		neighbors []int
+		next      int
+	}
+	cells := append([]int(nil), start...)
+	stack := []frame{{neighbors: l.joined(nil, start)}}
+	for len(stack) > 0 {
+		f := &stack[len(stack)-1]
+		if f.next == len(f.neighbors) {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		c := f.neighbors[f.next : f.next+dims]
+		f.next += dims
+		if !l.take(c, value) {
+			continue
+		}
+		cells = append(cells, c...)
+		if len(cells)/dims%checkEvery == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		stack = append(stack, frame{neighbors: l.joined(nil, c)})
+	}
+	return cells, nil
+}
+
+// coords returns points as coordinates of two axes, column then row.
+func coords(ps []point) [][]int {
+	flat := make([]int, 2*len(ps))
+	cs := make([][]int, len(ps))
+	for i, p := range ps {
+		flat[2*i], flat[2*i+1] = p.x, p.y
+		cs[i] = flat[2*i : 2*i+2 : 2*i+2]
+	}
+	return cs
+}
+
+// points returns cells of two axes laid one after another as points.
+func points(flat []int) []point {
+	ps := make([]point, len(flat)/2)
+	for i := range ps {
+		ps[i] = point{flat[2*i], flat[2*i+1]}
+	}
+	return ps
+}
+
+// fromCoords returns coordinates of two axes as points.
+func fromCoords(cs [][]int) []point {
+	ps := make([]point, len(cs))
+	for i, c := range cs {
+		ps[i] = point{c[0], c[1]}
+	}
+	return ps
+}
+
+// split returns cells laid one after another as a coordinate slice for each.
+func split(flat []int, dims int) [][]int {
+	cs := make([][]int, len(flat)/dims)
+	for i := range cs {
+		cs[i] = flat[i*dims : (i+1)*dims : (i+1)*dims]
+	}
+	return cs
+}
@@ -0,0 +1,46 @@
+package search
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+// strip is a labeling of one axis that wraps at its ends.
+type strip struct {
+	cells []int
+	seen  []bool
+}
+
+func (s strip) joined(ns, c []int) []int { return append(ns, c[0]+1, c[0]-1) }
+
+func (s strip) take(c []int, value int) bool {
+	i := wrap(c[0], len(s.cells))
+	if s.cells[i] != value || s.seen[i] {
+		return false
+	}
+	s.seen[i] = true
+	return true
+}
+
+func TestFlood(t *testing.T) {
+	s := strip{cells: []int{1, 1, 0, 2, 1}, seen: make([]bool, 5)}
+	s.seen[0] = true
+	got, err := flood(context.Background(), s, 1, []int{0}, 1)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	// the last cell of the strip is reached across its start, so it lies before it
+	if want := []int{0, 1, -1}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %v got %v", want, got)
+	}
+	if !reflect.DeepEqual(split(got, 1), [][]int{{0}, {1}, {-1}}) {
+		t.Fatalf("want the cells split one axis each, got %v", split(got, 1))
+	}
+	if ps := points([]int{1, 2, 3, 4}); !reflect.DeepEqual(ps, []point{{1, 2}, {3, 4}}) {
+		t.Fatalf("want points, got %v", ps)
+	}
+	if cs := coords([]point{{1, 2}, {3, 4}}); !reflect.DeepEqual(cs, [][]int{{1, 2}, {3, 4}}) {
+		t.Fatalf("want coordinates, got %v", cs)
+	}
+}
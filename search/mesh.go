@@ -0,0 +1,121 @@
+package search
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// voxel is a position in a volume as column, row and slice.
+type voxel [3]int
+
+// face is a square on the surface of a polycube, with its outward normal and its corners counterclockwise seen from
+// outside.
+type face struct {
+	normal  voxel
+	corners [4]voxel
+}
+
+// cubeFaces lists the faces of a unit cube. Each normal is also the step to the voxel beyond the face.
+var cubeFaces = []face{
+	{voxel{1, 0, 0}, [4]voxel{{1, 0, 0}, {1, 1, 0}, {1, 1, 1}, {1, 0, 1}}},
+	{voxel{-1, 0, 0}, [4]voxel{{0, 0, 0}, {0, 0, 1}, {0, 1, 1}, {0, 1, 0}}},
+	{voxel{0, 1, 0}, [4]voxel{{0, 1, 0}, {0, 1, 1}, {1, 1, 1}, {1, 1, 0}}},
+	{voxel{0, -1, 0}, [4]voxel{{0, 0, 0}, {1, 0, 0}, {1, 0, 1}, {0, 0, 1}}},
+	{voxel{0, 0, 1}, [4]voxel{{0, 0, 1}, {1, 0, 1}, {1, 1, 1}, {0, 1, 1}}},
+	{voxel{0, 0, -1}, [4]voxel{{0, 0, 0}, {0, 1, 0}, {1, 1, 0}, {1, 0, 0}}},
+}
+
+// surface returns the faces of the voxels of a three dimensional shape that are not shared with another of its
+// voxels. The shape is moved to the origin and then along the x axis by shift.
+func (c cluster) surface(shift int) []face {
+	var vs []voxel
+	set := make(map[voxel]bool)
+	for _, cell := range normalizeCoords(c.cells) {
+		v := voxel{cell[0], cell[1], cell[2]}
+		vs = append(vs, v)
+		set[v] = true
+	}
+	var faces []face
+	for _, v := range vs {
+		for _, cf := range cubeFaces {
+			if set[voxel{v[0] + cf.normal[0], v[1] + cf.normal[1], v[2] + cf.normal[2]}] {
+				continue
+			}
+			f := face{normal: cf.normal}
+			for i, k := range cf.corners {
+				f.corners[i] = voxel{v[0] + k[0] + shift, v[1] + k[1], v[2] + k[2]}
+			}
+			faces = append(faces, f)
+		}
+	}
+	return faces
+}
+
+// surfaces returns the surface of each unique shape with its cells where layout puts them, laid side by side along
+// the x axis with a voxel between them.
+func (s spaceState) surfaces() [][]face {
+	var all [][]face
+	shift := 0
+	for _, shp := range s.shapes {
+		laid := cluster{cells: s.layout(shp), value: shp.value}
+		all = append(all, laid.surface(shift))
+		shift += extent(normalizeCoords(laid.cells))[0] + 1
+	}
+	return all
+}
+
+// WriteOBJ writes each unique shape as an object of a Wavefront OBJ mesh of square faces, with the shapes side by
+// side along the x axis. It fails for a space that is not three dimensional.
+func (s spaceState) WriteOBJ(w io.Writer) error {
+	if s.Dims() != 3 {
+		return errorNotVolume
+	}
+	var b strings.Builder
+	vertices := make(map[voxel]int)
+	for i, faces := range s.surfaces() {
+		fmt.Fprintf(&b, "o shape%d\n", i+1)
+		var lines []string
+		for _, f := range faces {
+			var ids []string
+			for _, c := range f.corners {
+				id, ok := vertices[c]
+				if !ok {
+					id = len(vertices) + 1
+					vertices[c] = id
+					fmt.Fprintf(&b, "v %d %d %d\n", c[0], c[1], c[2])
+				}
+				ids = append(ids, fmt.Sprint(id))
+			}
+			lines = append(lines, "f "+strings.Join(ids, " "))
+		}
+		fmt.Fprintln(&b, strings.Join(lines, "\n"))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteSTL writes the unique shapes as an ASCII STL mesh, two triangles to each square face, with the shapes side by
+// side along the x axis. Like WriteOBJ it fails for a space that is not three dimensional.
+func (s spaceState) WriteSTL(w io.Writer) error {
+	if s.Dims() != 3 {
+		return errorNotVolume
+	}
+	var b strings.Builder
+	b.WriteString("solid shapes\n")
+	for _, faces := range s.surfaces() {
+		for _, f := range faces {
+			for _, tri := range [][3]int{{0, 1, 2}, {0, 2, 3}} {
+				fmt.Fprintf(&b, "facet normal %d %d %d\n outer loop\n", f.normal[0], f.normal[1], f.normal[2])
+				for _, i := range tri {
+					c := f.corners[i]
+					fmt.Fprintf(&b, "  vertex %d %d %d\n", c[0], c[1], c[2])
+				}
+				b.WriteString(" endloop\nendfacet\n")
+			}
+		}
+	}
+	b.WriteString("endsolid shapes\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,294 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const errorElement = stateError("structuring elements are cross, box, cross:N, box:N or rows of 0 and 1 of odd length separated by /")
+const errorStep = stateError("preprocessing steps are erode, dilate, open or close with an optional =element, fill, or min=N")
+const errorMorphLattice = stateError("preprocessing needs a square lattice")
+const errorMorphGrid = stateError("preprocessing needs a whole grid held cell by cell")
+
+// Element is a structuring element: the column and row offsets from a cell of the cells that erosion and dilation
+// look at.
+type Element [][2]int
+
+// Cross returns the cells within r steps of the center by edges, a plus sign when r is 1.
+func Cross(r int) Element {
+	var e Element
+	for dy := -r; dy <= r; dy++ {
+		for dx := -r; dx <= r; dx++ {
+			if abs(dx)+abs(dy) <= r {
+				e = append(e, [2]int{dx, dy})
+			}
+		}
+	}
+	return e
+}
+
+// Box returns the cells within r steps of the center by edges or corners, a 3 by 3 block when r is 1.
+func Box(r int) Element {
+	var e Element
+	for dy := -r; dy <= r; dy++ {
+		for dx := -r; dx <= r; dx++ {
+			e = append(e, [2]int{dx, dy})
+		}
+	}
+	return e
+}
+
+// parseElement reads an element by name and radius, as cross or box:2, or drawn as rows of 0 and 1 separated by
+// slashes with the center in the middle, as 010/111/010.
+func parseElement(s string) (Element, error) {
+	name, r := s, 1
+	if i := strings.IndexByte(s, ':'); i >= 0 {
+		n, err := strconv.Atoi(s[i+1:])
+		if err != nil || n < 0 {
+			return nil, errorElement
+		}
+		name, r = s[:i], n
+	}
+	switch name {
+	case "cross":
+		return Cross(r), nil
+	case "box":
+		return Box(r), nil
+	}
+	rows := strings.Split(s, "/")
+	if len(rows)%2 == 0 {
+		return nil, errorElement
+	}
+	var e Element
+	for y, row := range rows {
+		if len(row) != len(rows[0]) || len(row)%2 == 0 {
+			return nil, errorElement
+		}
+		for x, c := range row {
+			switch c {
+			case '1':
+				e = append(e, [2]int{x - len(row)/2, y - len(rows)/2})
+			case '0':
+			default:
+				return nil, errorElement
+			}
+		}
+	}
+	return e, nil
+}
+
+// Op is a step of preprocessing.
+type Op int
+
+const (
+	// Erode clears each cell that the element, centered on it, does not fit inside the cell's shape.
+	Erode Op = iota
+	// Dilate sets each empty cell that the element, centered on a cell of a shape, reaches, to the shape's label.
+	Dilate
+	// Open erodes and then dilates, removing shapes and parts of shapes the element does not fit in.
+	Open
+	// Close dilates and then erodes, filling gaps and bays the element does not fit in.
+	Close
+	// Fill sets the cells of each hole to the label of the shape around it.
+	Fill
+	// MinSize clears the shapes with fewer cells than the step's size.
+	MinSize
+)
+
+var ops = []string{"erode", "dilate", "open", "close", "fill", "min"}
+
+func (o Op) String() string { return ops[o] }
+
+// Step is an operation with the element or size it takes.
+type Step struct {
+	Op Op
+	// Element is the structuring element of Erode, Dilate, Open and Close, Cross(1) if nil.
+	Element Element
+	// Size is the fewest cells a shape kept by MinSize has.
+	Size int
+	// text is the step as it was written, if it was.
+	text string
+}
+
+// Pipeline is a list of steps run in order on a grid before it is searched. Each step is wrap aware: on a torus
+// elements and shapes reach across the edges, and on a plane the cells past an edge are empty.
+type Pipeline []Step
+
+func (p Pipeline) String() string {
+	steps := make([]string, len(p))
+	for i, s := range p {
+		steps[i] = s.text
+		if s.text == "" {
+			steps[i] = s.Op.String()
+		}
+		if s.text == "" && s.Op == MinSize {
+			steps[i] += "=" + strconv.Itoa(s.Size)
+		}
+	}
+	return strings.Join(steps, ",")
+}
+
+// UnmarshalText accepts steps separated by commas, each an operation name with the element or size it takes after
+// an equals sign, as in open=box,fill,min=3.
+func (p *Pipeline) UnmarshalText(b []byte) error {
+	*p = nil
+	if len(b) == 0 {
+		return nil
+	}
+	for _, text := range strings.Split(string(b), ",") {
+		name, arg := text, ""
+		if i := strings.IndexByte(text, '='); i >= 0 {
+			name, arg = text[:i], text[i+1:]
+		}
+		i, err := parseOption(name, ops)
+		if err != nil {
+			return fmt.Errorf("%s, found %q", errorStep, text)
+		}
+		s := Step{Op: Op(i), text: text}
+		switch {
+		case s.Op == MinSize:
+			if s.Size, err = strconv.Atoi(arg); err != nil {
+				return fmt.Errorf("%s, found %q", errorStep, text)
+			}
+		case s.Op == Fill:
+			if arg != "" {
+				return fmt.Errorf("%s, found %q", errorStep, text)
+			}
+		case arg != "":
+			if s.Element, err = parseElement(arg); err != nil {
+				return err
+			}
+		}
+		*p = append(*p, s)
+	}
+	return nil
+}
+
+// Apply runs the steps on a copy of the grid, searching it with the options where a step needs its shapes, and
+// returns the copy.
+func (p Pipeline) Apply(ctx context.Context, g [][]int, opts Options) ([][]int, error) {
+	if err := Dense(g).check(); err != nil {
+		return nil, err
+	}
+	if opts.Lattice != Square {
+		return nil, errorMorphLattice
+	}
+	opts.Preprocess, opts.Filter, opts.Progress = nil, nil, nil
+	out := make([][]int, len(g))
+	for y, row := range g {
+		out[y] = append([]int(nil), row...)
+	}
+	for _, s := range p {
+		e := s.Element
+		if e == nil {
+			e = Cross(1)
+		}
+		var err error
+		switch s.Op {
+		case Erode:
+			out = opts.erode(out, e)
+		case Dilate:
+			out = opts.dilate(out, e)
+		case Open:
+			out = opts.dilate(opts.erode(out, e), e)
+		case Close:
+			out = opts.erode(opts.dilate(out, e), e)
+		case Fill:
+			err = opts.fill(ctx, out)
+		case MinSize:
+			err = opts.minSize(ctx, out, s.Size)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// at returns the value of the cell at x, y, wrapping around a torus and empty past the edge of a plane.
+func (o Options) at(g [][]int, x, y int) int {
+	rows, cols := len(g), len(g[0])
+	if o.Topology == Plane && (x < 0 || y < 0 || x >= cols || y >= rows) {
+		return o.Background
+	}
+	return g[wrap(y, rows)][wrap(x, cols)]
+}
+
+// erode keeps the cells of a shape where every cell the element reaches holds the same label.
+func (o Options) erode(g [][]int, e Element) [][]int {
+	out := make([][]int, len(g))
+	for y, row := range g {
+		out[y] = make([]int, len(row))
+		for x, v := range row {
+			out[y][x] = v
+			if v == o.Background {
+				continue
+			}
+			for _, d := range e {
+				if o.at(g, x+d[0], y+d[1]) != v {
+					out[y][x] = o.Background
+					break
+				}
+			}
+		}
+	}
+	return out
+}
+
+// dilate sets each empty cell to the label of the first cell of a shape that reaches it through the element.
+func (o Options) dilate(g [][]int, e Element) [][]int {
+	out := make([][]int, len(g))
+	for y, row := range g {
+		out[y] = make([]int, len(row))
+		for x, v := range row {
+			out[y][x] = v
+			if v != o.Background {
+				continue
+			}
+			for _, d := range e {
+				if n := o.at(g, x-d[0], y-d[1]); n != o.Background {
+					out[y][x] = n
+					break
+				}
+			}
+		}
+	}
+	return out
+}
+
+// fill sets the cells of the holes of each shape to its label.
+func (o Options) fill(ctx context.Context, g [][]int) error {
+	s, err := NewWithContext(ctx, g, o)
+	if err != nil {
+		return err
+	}
+	for _, c := range s.components {
+		for _, h := range c.holes {
+			for _, p := range h {
+				t := p.transform(s.rows, s.cols)
+				g[t.y][t.x] = c.value
+			}
+		}
+	}
+	return nil
+}
+
+// minSize clears the cells of the shapes with fewer than n cells.
+func (o Options) minSize(ctx context.Context, g [][]int, n int) error {
+	s, err := NewWithContext(ctx, g, o)
+	if err != nil {
+		return err
+	}
+	for _, c := range s.components {
+		if len(c.points) >= n {
+			continue
+		}
+		for _, p := range c.points {
+			t := p.transform(s.rows, s.cols)
+			g[t.y][t.x] = o.Background
+		}
+	}
+	return nil
+}
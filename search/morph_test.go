@@ -0,0 +1,175 @@
+package search
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestPipeline(t *testing.T) {
+	noisy := [][]int{
+		{0, 0, 0, 0, 0, 1},
+		{0, 1, 1, 1, 0, 0},
+		{0, 1, 1, 1, 0, 0},
+		{0, 1, 1, 1, 0, 0},
+		{1, 0, 0, 0, 0, 0},
+	}
+	tt := []struct {
+		steps string
+		grid  [][]int
+		opts  Options
+		want  [][]int
+	}{
+		{
+			steps: "erode",
+			grid:  noisy,
+			opts:  Options{Topology: Plane},
+			want:  [][]int{{0, 0, 0, 0, 0, 0}, {0, 0, 0, 0, 0, 0}, {0, 0, 1, 0, 0, 0}, {0, 0, 0, 0, 0, 0}, {0, 0, 0, 0, 0, 0}},
+		},
+		{
+			// opening keeps the block, as a cross, and drops the specks
+			steps: "open",
+			grid:  noisy,
+			opts:  Options{Topology: Plane},
+			want:  [][]int{{0, 0, 0, 0, 0, 0}, {0, 0, 1, 0, 0, 0}, {0, 1, 1, 1, 0, 0}, {0, 0, 1, 0, 0, 0}, {0, 0, 0, 0, 0, 0}},
+		},
+		{
+			steps: "open=box",
+			grid:  noisy,
+			opts:  Options{Topology: Plane},
+			want:  [][]int{{0, 0, 0, 0, 0, 0}, {0, 1, 1, 1, 0, 0}, {0, 1, 1, 1, 0, 0}, {0, 1, 1, 1, 0, 0}, {0, 0, 0, 0, 0, 0}},
+		},
+		{
+			steps: "min=2",
+			grid:  noisy,
+			opts:  Options{Topology: Plane},
+			want:  [][]int{{0, 0, 0, 0, 0, 0}, {0, 1, 1, 1, 0, 0}, {0, 1, 1, 1, 0, 0}, {0, 1, 1, 1, 0, 0}, {0, 0, 0, 0, 0, 0}},
+		},
+		{
+			// on a torus the corner cells touch
+			steps: "min=2",
+			grid:  noisy,
+			opts:  Options{Connectivity: EightWay},
+			want:  noisy,
+		},
+		{
+			steps: "dilate=100/000/000",
+			grid:  [][]int{{0, 0, 0}, {0, 2, 0}, {0, 0, 0}},
+			opts:  Options{Topology: Plane},
+			want:  [][]int{{2, 0, 0}, {0, 2, 0}, {0, 0, 0}},
+		},
+		{
+			// dilating wraps around a torus, and a cell that two labels reach takes the first the element finds
+			steps: "dilate",
+			grid:  [][]int{{1, 0, 0, 0}, {0, 0, 0, 2}, {0, 0, 0, 0}},
+			want:  [][]int{{1, 1, 0, 2}, {2, 0, 2, 2}, {1, 0, 0, 2}},
+		},
+		{
+			steps: "close",
+			grid:  [][]int{{0, 0, 0, 0, 0}, {0, 1, 1, 1, 0}, {0, 1, 0, 1, 0}, {0, 1, 1, 1, 0}, {0, 0, 0, 0, 0}},
+			opts:  Options{Topology: Plane},
+			want:  [][]int{{0, 0, 0, 0, 0}, {0, 1, 1, 1, 0}, {0, 1, 1, 1, 0}, {0, 1, 1, 1, 0}, {0, 0, 0, 0, 0}},
+		},
+		{
+			steps: "fill",
+			grid:  [][]int{{1, 1, 1, 0}, {1, 0, 1, 0}, {1, 1, 1, 0}, {0, 0, 0, 0}},
+			want:  [][]int{{1, 1, 1, 0}, {1, 1, 1, 0}, {1, 1, 1, 0}, {0, 0, 0, 0}},
+		},
+		{
+			steps: "erode=cross:0,dilate=box:0",
+			grid:  noisy,
+			want:  noisy,
+		},
+	}
+	for i, tc := range tt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var p Pipeline
+			if err := p.UnmarshalText([]byte(tc.steps)); err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			if p.String() != tc.steps {
+				t.Fatalf("want %q got %q", tc.steps, p.String())
+			}
+			got, err := p.Apply(context.Background(), tc.grid, tc.opts)
+			if err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("want %v got %v", tc.want, got)
+			}
+		})
+	}
+	if noisy[0][5] != 1 {
+		t.Fatal("want the grid given left as it was")
+	}
+}
+
+func TestPipelineErrors(t *testing.T) {
+	tt := []struct {
+		steps string
+		want  string
+	}{
+		{steps: "smooth", want: errorStep.Error()},
+		{steps: "min", want: errorStep.Error()},
+		{steps: "fill=box", want: errorStep.Error()},
+		{steps: "open=ring", want: errorElement.Error()},
+		{steps: "open=box:x", want: errorElement.Error()},
+		{steps: "open=11/11", want: errorElement.Error()},
+		{steps: "open=010/1/010", want: errorElement.Error()},
+	}
+	for i, tc := range tt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var p Pipeline
+			if err := p.UnmarshalText([]byte(tc.steps)); err == nil || !strings.HasPrefix(err.Error(), tc.want) {
+				t.Fatalf("want error %q got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestPreprocess(t *testing.T) {
+	grid := [][]int{
+		{1, 0, 0, 0, 0, 0},
+		{0, 0, 1, 1, 0, 0},
+		{0, 0, 1, 1, 0, 1},
+		{0, 0, 0, 0, 0, 0},
+	}
+	var p Pipeline
+	if err := p.UnmarshalText([]byte("min=2")); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	s, err := NewWithOptions(grid, Options{Topology: Plane, Preprocess: p})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if r := s.Result(); r.Components != 1 || len(r.Shapes[0].Cells) != 4 {
+		t.Fatalf("want only the block found, got %+v", r)
+	}
+	if _, err := NewWithOptions(grid, Options{Lattice: Hex, Preprocess: p}); err != errorMorphLattice {
+		t.Fatalf("want error %v got %v", errorMorphLattice, err)
+	}
+	if _, err := NewStream(context.Background(), strings.NewReader("1 0\n"), Options{Preprocess: p}); err != errorMorphGrid {
+		t.Fatalf("want error %v got %v", errorMorphGrid, err)
+	}
+	if _, err := NewFromBits(context.Background(), NewBits(2, 2), Options{Preprocess: p}); err != errorMorphGrid {
+		t.Fatalf("want error %v got %v", errorMorphGrid, err)
+	}
+
+	// a search stopped while cleaning up the grid has found nothing yet, but still returns a result
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, steps := range []string{"min=2", "fill"} {
+		if err := p.UnmarshalText([]byte(steps)); err != nil {
+			t.Fatal("unexpected error", err)
+		}
+		s, err := NewWithContext(ctx, grid, Options{Preprocess: p})
+		if err != context.Canceled || s == nil {
+			t.Fatalf("%s: want a result with error %v got %v", steps, context.Canceled, err)
+		}
+		if r := s.Result(); !r.Partial || r.Components != 0 || r.Rows != 4 || r.Cols != 6 {
+			t.Fatalf("%s: want an empty partial result got %+v", steps, r)
+		}
+	}
+}
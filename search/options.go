@@ -0,0 +1,227 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Options controls how a grid is searched.
+type Options struct {
+	// Background is the cell value treated as empty space. Every other value is a label, and shapes are
+	// connected cells sharing the same label.
+	Background int
+	// AcrossValues treats shapes with the same layout as duplicates even when their labels differ.
+	AcrossValues bool
+	// FillHoles compares shapes as if their holes were filled in, so a ring and a solid block with the same outline
+	// are duplicates.
+	FillHoles bool
+	// Topology decides whether shapes wrap from one edge of the grid to the opposite edge.
+	Topology Topology
+	// Connectivity decides whether cells touching only at a corner belong to the same shape.
+	Connectivity Connectivity
+	// Equivalence decides which shapes count as duplicates of each other.
+	Equivalence Equivalence
+	// Lattice decides the shape of the cells and which cells are neighbors.
+	Lattice Lattice
+	// Glyphs are the characters Print draws cells with.
+	Glyphs Glyphs
+	// Preprocess runs on a grid before it is searched, to clean up noise. It needs a Dense grid on a square lattice.
+	Preprocess Pipeline
+	// Filter leaves out the shapes its conditions do not hold for.
+	Filter Filter
+	// Parallel splits the grid into bands of rows and labels them at the same time, using up to GOMAXPROCS
+	// goroutines. The shapes found are the same as a serial search.
+	Parallel bool
+	// Progress, if set, is called as rows of the grid are scanned, or after each band when searching in parallel.
+	// Bits and Sparse grids pass over rows holding nothing to search in one step and report once past them, so
+	// RowsScanned may grow by more than one row between calls.
+	Progress func(Progress)
+
+	runes bool
+}
+
+const errorOption = stateError("unknown option value")
+
+// Topology is the surface the grid is drawn on.
+type Topology int
+
+const (
+	// Torus joins each edge of the grid to the opposite edge.
+	Torus Topology = iota
+	// Plane ends the grid at its edges.
+	Plane
+)
+
+var topologies = []string{"torus", "plane"}
+
+func (t Topology) String() string { return topologies[t] }
+
+// UnmarshalText accepts the name of a topology.
+func (t *Topology) UnmarshalText(b []byte) error {
+	i, err := parseOption(string(b), topologies)
+	*t = Topology(i)
+	return err
+}
+
+// Connectivity is the way cells join into shapes.
+type Connectivity int
+
+const (
+	// FourWay joins cells that share an edge.
+	FourWay Connectivity = iota
+	// EightWay joins cells that share an edge or a corner.
+	EightWay
+)
+
+var connectivities = []string{"4", "8"}
+
+func (c Connectivity) String() string { return connectivities[c] }
+
+// UnmarshalText accepts 4 or 8.
+func (c *Connectivity) UnmarshalText(b []byte) error {
+	i, err := parseOption(string(b), connectivities)
+	*c = Connectivity(i)
+	return err
+}
+
+// Equivalence is the set of moves that turn a shape into a duplicate of itself.
+type Equivalence int
+
+const (
+	// Translation counts shapes as duplicates when one can be slid onto the other.
+	Translation Equivalence = iota
+	// Rotation also allows turning a shape by quarter turns.
+	Rotation
+	// Reflection also allows flipping a shape over.
+	Reflection
+)
+
+var equivalences = []string{"translation", "rotation", "reflection"}
+
+func (e Equivalence) String() string { return equivalences[e] }
+
+// UnmarshalText accepts the name of an equivalence.
+func (e *Equivalence) UnmarshalText(b []byte) error {
+	i, err := parseOption(string(b), equivalences)
+	*e = Equivalence(i)
+	return err
+}
+
+// Lattice is the tiling of the plane that a grid's cells make up.
+type Lattice int
+
+const (
+	// Square cells are joined as Connectivity decides.
+	Square Lattice = iota
+	// Hex cells each have six neighbors and ignore Connectivity. Rows of a grid are staggered, with odd rows pushed
+	// half a cell to the right, so column and row are offset coordinates. A hex grid wrapped on a torus must have
+	// an even number of rows for the stagger to line up across the top and bottom edges.
+	Hex
+	// Triangle cells each have three neighbors and ignore Connectivity. A cell whose column and row add up to an
+	// even number points up and its third neighbor is below it, the others point down. A triangular grid wrapped on
+	// a torus must have an even number of rows and columns.
+	Triangle
+)
+
+var lattices = []string{"square", "hex", "triangle"}
+
+func (l Lattice) String() string { return lattices[l] }
+
+// UnmarshalText accepts the name of a lattice.
+func (l *Lattice) UnmarshalText(b []byte) error {
+	i, err := parseOption(string(b), lattices)
+	*l = Lattice(i)
+	return err
+}
+
+// Glyphs are the characters shapes are drawn with. A zero Set draws set cells as X and a zero Unset draws empty
+// cells as spaces. Triangles are always drawn pointing up or down as ^ and v.
+type Glyphs struct {
+	Set, Unset rune
+}
+
+const errorGlyphs = stateError("glyphs are two characters, one for set cells and one for empty cells")
+
+func (g Glyphs) String() string {
+	g = g.orDefault()
+	return string([]rune{g.Set, g.Unset})
+}
+
+// UnmarshalText accepts the character for set cells followed by the one for empty cells.
+func (g *Glyphs) UnmarshalText(b []byte) error {
+	rs := []rune(string(b))
+	if len(rs) != 2 {
+		return errorGlyphs
+	}
+	g.Set, g.Unset = rs[0], rs[1]
+	return nil
+}
+
+// orDefault fills in the characters left zero.
+func (g Glyphs) orDefault() Glyphs {
+	if g.Set == 0 {
+		g.Set = 'X'
+	}
+	if g.Unset == 0 {
+		g.Unset = ' '
+	}
+	return g
+}
+
+// checkSize returns an error if a grid of the given size cannot be searched with the options.
+func (o Options) checkSize(rows, cols int) error {
+	switch {
+	case o.Topology != Torus:
+	case o.Lattice == Hex && rows%2 != 0:
+		return errorHexRows
+	case o.Lattice == Triangle && (rows%2 != 0 || cols%2 != 0):
+		return errorTriangleSize
+	}
+	return nil
+}
+
+func parseOption(s string, names []string) (int, error) {
+	for i, n := range names {
+		if strings.EqualFold(s, n) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("%s %q, want one of %s", errorOption, s, strings.Join(names, ", "))
+}
+
+// squareTurns are the moves of each equivalence on a square lattice, as for a space of two axes.
+var squareTurns = [][][]int{axisTurns(2, Translation), axisTurns(2, Rotation), axisTurns(2, Reflection)}
+
+// canonical is the smallest key among the shape's layouts reachable by the moves of the equivalence. Shapes on a
+// square lattice are compared as any space is, by canonicalCoords. Shapes on a hex lattice are compared in axial
+// coordinates, where sliding a shape never changes its layout, and shapes on a triangular lattice by the centers of
+// their cells.
+func (s shape) canonical(e Equivalence, l Lattice) string {
+	if l == Square {
+		return canonicalCoords(coords(s.points), squareTurns[e], nil, nil)
+	}
+	ps, key := axial(s.points), func(ps []point) string { return (shape{points: ps}).key() }
+	if l == Triangle {
+		ps, key = centers(s.points), centersKey
+	}
+	best := key(ps)
+	if e == Translation {
+		return best
+	}
+	// Both lattices have the six turns of a hexagon.
+	const turns = 6
+	for i := 0; i < 2*turns; i++ {
+		if i == turns {
+			if e != Reflection {
+				break
+			}
+			ps = mirrorHex(ps)
+		} else if i > 0 {
+			ps = rotateHex(ps)
+		}
+		if k := key(ps); k < best {
+			best = k
+		}
+	}
+	return best
+}
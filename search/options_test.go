@@ -0,0 +1,188 @@
+package search
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+)
+
+func TestOptions(t *testing.T) {
+	tt := []struct {
+		grid       [][]int
+		opts       Options
+		wantShapes int
+		wantCount  int
+	}{
+		{
+			// the two cells join across the left and right edges on a torus
+			grid: [][]int{
+				{1, 0, 1},
+				{0, 0, 0},
+			},
+			wantShapes: 1,
+			wantCount:  1,
+		},
+		{
+			grid: [][]int{
+				{1, 0, 1},
+				{0, 0, 0},
+			},
+			opts:       Options{Topology: Plane},
+			wantShapes: 1,
+			wantCount:  2,
+		},
+		{
+			grid: [][]int{
+				{1, 0, 0, 0},
+				{0, 1, 0, 0},
+				{0, 0, 0, 0},
+			},
+			opts:       Options{Topology: Plane, Connectivity: EightWay},
+			wantShapes: 1,
+			wantCount:  1,
+		},
+		{
+			// an L and the same L turned a quarter turn
+			grid: [][]int{
+				{1, 0, 0, 1, 1, 1},
+				{1, 0, 0, 1, 0, 0},
+				{1, 1, 0, 0, 0, 0},
+			},
+			opts:       Options{Topology: Plane},
+			wantShapes: 2,
+		},
+		{
+			grid: [][]int{
+				{1, 0, 0, 1, 1, 1},
+				{1, 0, 0, 1, 0, 0},
+				{1, 1, 0, 0, 0, 0},
+			},
+			opts:       Options{Topology: Plane, Equivalence: Rotation},
+			wantShapes: 1,
+			wantCount:  2,
+		},
+		{
+			// an L and its mirror image
+			grid: [][]int{
+				{1, 0, 0, 0, 1},
+				{1, 0, 0, 0, 1},
+				{1, 1, 0, 1, 1},
+			},
+			opts:       Options{Topology: Plane, Equivalence: Rotation},
+			wantShapes: 2,
+		},
+		{
+			grid: [][]int{
+				{1, 0, 0, 0, 1},
+				{1, 0, 0, 0, 1},
+				{1, 1, 0, 1, 1},
+			},
+			opts:       Options{Topology: Plane, Equivalence: Reflection},
+			wantShapes: 1,
+			wantCount:  2,
+		},
+	}
+
+	for i, tc := range tt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			s, err := NewWithOptions(tc.grid, tc.opts)
+			if err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			r := s.Result()
+			if len(r.Shapes) != tc.wantShapes {
+				t.Fatalf("want %d shapes got %d", tc.wantShapes, len(r.Shapes))
+			}
+			if tc.wantCount > 0 && r.Shapes[0].Count != tc.wantCount {
+				t.Fatalf("want count %d got %d", tc.wantCount, r.Shapes[0].Count)
+			}
+		})
+	}
+}
+
+func TestPlaneHoles(t *testing.T) {
+	// on a plane the region in the corner is cut off by the edges, not the shape
+	grid := [][]int{
+		{0, 1, 0, 0},
+		{1, 1, 0, 0},
+		{0, 0, 0, 0},
+	}
+	s, err := NewWithOptions(grid, Options{Topology: Plane})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if len(s.components[0].holes) != 0 {
+		t.Fatal("want no holes")
+	}
+	s, err = New(grid)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if len(s.components[0].holes) != 0 {
+		t.Fatal("want no holes")
+	}
+}
+
+func TestUnmarshalOptions(t *testing.T) {
+	var top Topology
+	if err := top.UnmarshalText([]byte("Plane")); err != nil || top != Plane {
+		t.Fatalf("got %v %v", top, err)
+	}
+	var c Connectivity
+	if err := c.UnmarshalText([]byte("8")); err != nil || c != EightWay {
+		t.Fatalf("got %v %v", c, err)
+	}
+	var e Equivalence
+	if err := e.UnmarshalText([]byte("reflection")); err != nil || e != Reflection {
+		t.Fatalf("got %v %v", e, err)
+	}
+	if err := e.UnmarshalText([]byte("shear")); err == nil {
+		t.Fatal("expected error")
+	}
+	var g Glyphs
+	if err := g.UnmarshalText([]byte("#.")); err != nil || g != (Glyphs{'#', '.'}) {
+		t.Fatalf("got %v %v", g, err)
+	}
+	if err := g.UnmarshalText([]byte("#")); err == nil {
+		t.Fatal("expected error")
+	}
+	if s := (Glyphs{}).String(); s != "X " {
+		t.Fatalf("got %q", s)
+	}
+}
+
+func TestGlyphs(t *testing.T) {
+	grid := [][]int{
+		{0, 0, 0, 0},
+		{1, 1, 0, 0},
+		{1, 0, 0, 0},
+		{0, 0, 0, 0},
+	}
+	tt := []struct {
+		opts Options
+		want string
+	}{
+		{opts: Options{Glyphs: Glyphs{Set: '#', Unset: '.'}}, want: "    ##\n    #.\n------\n"},
+		{opts: Options{Glyphs: Glyphs{Set: '█'}}, want: "    ██\n    █ \n------\n"},
+		{opts: Options{Lattice: Hex, Glyphs: Glyphs{Set: '#', Unset: '.'}}, want: "    .#.#\n    #...\n--------\n"},
+		{opts: Options{Lattice: Triangle, Glyphs: Glyphs{Unset: '·'}}, want: "    ^v\n    v·\n------\n"},
+	}
+	for i, tc := range tt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			g := grid
+			if tc.opts.Lattice == Triangle {
+				// a triangle pointing up with the two below and right of it
+				g = [][]int{{0, 0, 0, 0}, {0, 0, 0, 0}, {1, 1, 0, 0}, {1, 0, 0, 0}}
+			}
+			s, err := NewWithOptions(g, tc.opts)
+			if err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			var b bytes.Buffer
+			s.Print(&b)
+			if b.String() != tc.want {
+				t.Fatalf("want\n%q\ngot\n%q", tc.want, b.String())
+			}
+		})
+	}
+}
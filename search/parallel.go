@@ -0,0 +1,221 @@
+package search
+
+import (
+	"context"
+	"sync"
+)
+
+// unionFind holds, for each cell index, the index of a cell in the same component and where the cell lies from it,
+// unrolled across the edges of a torus. Following the links ends at the component's root, which is always its first
+// cell in row order.
+type unionFind struct {
+	parent []int
+	offset []point
+}
+
+func newUnionFind(n int) unionFind {
+	return unionFind{parent: make([]int, n), offset: make([]point, n)}
+}
+
+// find returns the root of i and where i lies from it, shortening the path to it as it goes.
+func (u unionFind) find(i int) (int, point) {
+	var off point
+	for u.parent[i] != i {
+		if p := u.parent[i]; u.parent[p] != p {
+			u.offset[i] = point{u.offset[i].x + u.offset[p].x, u.offset[i].y + u.offset[p].y}
+			u.parent[i] = u.parent[p]
+		}
+		off = point{off.x + u.offset[i].x, off.y + u.offset[i].y}
+		i = u.parent[i]
+	}
+	return i, off
+}
+
+// union joins the components of a and b, where b lies d from a. Cells already joined are left as they are, so a
+// component that wraps all the way around a torus is unrolled along the first links found.
+func (u unionFind) union(a, b int, d point) {
+	ra, oa := u.find(a)
+	rb, ob := u.find(b)
+	switch {
+	case ra < rb:
+		u.parent[rb], u.offset[rb] = ra, point{d.x + oa.x - ob.x, d.y + oa.y - ob.y}
+	case rb < ra:
+		u.parent[ra], u.offset[ra] = rb, point{ob.x - oa.x - d.x, ob.y - oa.y - d.y}
+	}
+}
+
+// scanParallel finds the same components as scan, in the same order, using up to workers goroutines. Bands of rows
+// are labeled at the same time, then the labels are joined across the seams between bands, including the seam
+// between the last and first rows on a torus. Finally the cells are gathered by their root, in row order, each
+// placed where it lies from its root, so a component holds the same cells as a serial search finds, with the same
+// key. If ctx is done part way through, the components whose cells were all labeled are kept.
+func (s *state) scanParallel(ctx context.Context, workers int) error {
+	bands := s.bands(workers)
+	u := newUnionFind(s.rows * s.cols)
+	done := make([]int, len(bands))
+	labeled := func(p point) bool {
+		row := p.transform(s.rows, s.cols).y
+		for i, b := range bands {
+			if row >= b.first && row < b.end {
+				return row < b.first+done[i]
+			}
+		}
+		return false
+	}
+	inBand := func(b band, p point) bool {
+		row := p.transform(s.rows, s.cols).y
+		return row >= b.first && row < b.end
+	}
+
+	var mu sync.Mutex
+	var scanned int
+	err := each(ctx, len(bands), func(i int) error {
+		b := bands[i]
+		for row := b.first; row < b.end; row++ {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			for col := 0; col < s.cols; col++ {
+				p := getPoint(col, row)
+				at := s.cellIndex(p)
+				u.parent[at] = at
+				v := s.value(p)
+				if v == s.opts.Background {
+					continue
+				}
+				// Only cells already labeled are joined, so each pair is joined once.
+				for _, n := range s.neighbors(p) {
+					if ni := s.cellIndex(n); ni < at && inBand(b, n) && s.value(n) == v {
+						u.union(at, ni, point{n.x - p.x, n.y - p.y})
+					}
+				}
+			}
+			done[i]++
+		}
+		if s.opts.Progress != nil {
+			mu.Lock()
+			scanned += b.end - b.first
+			s.opts.Progress(Progress{RowsScanned: scanned, Rows: s.rows})
+			mu.Unlock()
+		}
+		return nil
+	})
+
+	// Every connection across a seam has one end in the first row of a band.
+	for i, b := range bands {
+		if done[i] == 0 {
+			continue
+		}
+		for col := 0; col < s.cols; col++ {
+			p := getPoint(col, b.first)
+			v := s.value(p)
+			if v == s.opts.Background {
+				continue
+			}
+			for _, n := range s.neighbors(p) {
+				if !inBand(b, n) && labeled(n) && s.value(n) == v {
+					u.union(s.cellIndex(p), s.cellIndex(n), point{n.x - p.x, n.y - p.y})
+				}
+			}
+		}
+	}
+
+	// A component with a cell joined to one left unlabeled is not finished.
+	var unfinished map[int]bool
+	if err != nil {
+		unfinished = make(map[int]bool)
+		for i, b := range bands {
+			for row := b.first; row < b.first+done[i]; row++ {
+				for col := 0; col < s.cols; col++ {
+					p := getPoint(col, row)
+					v := s.value(p)
+					if v == s.opts.Background {
+						continue
+					}
+					for _, n := range s.neighbors(p) {
+						if !labeled(n) && s.value(n) == v {
+							root, _ := u.find(s.cellIndex(p))
+							unfinished[root] = true
+						}
+					}
+				}
+			}
+		}
+	}
+
+	component := make(map[int]int)
+	for i, b := range bands {
+		for row := b.first; row < b.first+done[i]; row++ {
+			for col := 0; col < s.cols; col++ {
+				p := getPoint(col, row)
+				v := s.value(p)
+				if v == s.opts.Background {
+					continue
+				}
+				root, off := u.find(s.cellIndex(p))
+				if unfinished[root] {
+					continue
+				}
+				c, ok := component[root]
+				if !ok {
+					c = len(s.components)
+					component[root] = c
+					s.components = append(s.components, shape{value: v})
+				}
+				first := getPoint(root%s.cols, root/s.cols)
+				s.components[c].points = append(s.components[c].points, point{first.x + off.x, first.y + off.y})
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if s.opts.Progress != nil {
+		s.opts.Progress(Progress{RowsScanned: s.rows, Rows: s.rows, Components: len(s.components)})
+	}
+	return nil
+}
+
+// band is a run of rows from first up to but not including end.
+type band struct {
+	first, end int
+}
+
+// bands splits the rows into at most n bands of nearly equal size.
+func (s state) bands(n int) []band {
+	if n > s.rows {
+		n = s.rows
+	}
+	if n < 1 {
+		n = 1
+	}
+	var bs []band
+	for i := 0; i < n; i++ {
+		bs = append(bs, band{first: i * s.rows / n, end: (i + 1) * s.rows / n})
+	}
+	return bs
+}
+
+func (s state) cellIndex(p point) int {
+	return p.index(s.rows, s.cols)
+}
+
+// each runs fn for 0 through n-1 in separate goroutines and returns the first error.
+func each(ctx context.Context, n int, fn func(int) error) error {
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = fn(i)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
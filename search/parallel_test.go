@@ -0,0 +1,151 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func randomGrid(r *rand.Rand, rows, cols, values int, density float64) [][]int {
+	grid := make([][]int, rows)
+	for i := range grid {
+		grid[i] = make([]int, cols)
+		for j := range grid[i] {
+			if r.Float64() < density {
+				grid[i][j] = 1 + r.Intn(values)
+			}
+		}
+	}
+	return grid
+}
+
+func TestScanParallel(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	opts := []Options{
+		{},
+		{Topology: Plane},
+		{Connectivity: EightWay},
+		{Topology: Plane, Connectivity: EightWay, Equivalence: Reflection},
+		{Topology: Plane, Lattice: Hex, Equivalence: Rotation},
+		{Topology: Plane, Lattice: Triangle, Equivalence: Reflection},
+	}
+	for i := 0; i < 40; i++ {
+		grid := randomGrid(r, 1+r.Intn(30), 1+r.Intn(30), 1+r.Intn(3), 0.3+r.Float64()*0.4)
+		for j, o := range opts {
+			for _, workers := range []int{1, 2, 3, 7, 64} {
+				t.Run(strconv.Itoa(i)+"/"+strconv.Itoa(j)+"/"+strconv.Itoa(workers), func(t *testing.T) {
+					serial, err := NewWithOptions(grid, o)
+					if err != nil {
+						t.Fatal("unexpected error", err)
+					}
+					parallel, err := newState(grid, o)
+					if err != nil {
+						t.Fatal("unexpected error", err)
+					}
+					if err := parallel.scanParallel(context.Background(), workers); err != nil {
+						t.Fatal("unexpected error", err)
+					}
+					if err := parallel.findHoles(context.Background()); err != nil {
+						t.Fatal("unexpected error", err)
+					}
+					parallel.dedupe()
+
+					if len(serial.components) != len(parallel.components) {
+						t.Fatalf("want %d components got %d", len(serial.components), len(parallel.components))
+					}
+					for k, c := range serial.components {
+						pc := parallel.components[k]
+						if len(c.points) != len(pc.points) || serial.key(c) != parallel.key(pc) {
+							t.Fatalf("component %d differs", k)
+						}
+						cells := make(map[point]bool)
+						for _, p := range c.points {
+							cells[p.transform(serial.rows, serial.cols)] = true
+						}
+						for m, p := range pc.points {
+							if !cells[p.transform(serial.rows, serial.cols)] {
+								t.Fatalf("component %d differs at point %d", k, m)
+							}
+						}
+					}
+					var want, got bytes.Buffer
+					serial.Print(&want)
+					parallel.Print(&got)
+					if want.String() != got.String() {
+						t.Fatalf("want\n%s\ngot\n%s", want.String(), got.String())
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestParallelOption(t *testing.T) {
+	grid := randomGrid(rand.New(rand.NewSource(2)), 50, 50, 2, 0.5)
+	serial, err := New(grid)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	var reports []Progress
+	parallel, err := NewWithOptions(grid, Options{Parallel: true, Progress: func(p Progress) {
+		reports = append(reports, p)
+	}})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if len(serial.shapes) != len(parallel.shapes) {
+		t.Fatalf("want %d shapes got %d", len(serial.shapes), len(parallel.shapes))
+	}
+	last := reports[len(reports)-1]
+	if last.RowsScanned != 50 || last.Components != len(serial.components) {
+		t.Fatalf("unexpected final progress %+v", last)
+	}
+}
+
+func TestScanParallelCancelled(t *testing.T) {
+	grid := randomGrid(rand.New(rand.NewSource(4)), 40, 40, 2, 0.5)
+	serial, err := New(grid)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	// each component is known by its first cell, which a finished one shares with the serial search
+	first := make(map[point]shape)
+	for _, c := range serial.components {
+		first[c.points[0]] = c
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	parallel, err := newState(grid, Options{Progress: func(Progress) {
+		// stop once a band is labeled, leaving the others part way through unless they are done already
+		cancel()
+	}})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if err := parallel.scanParallel(ctx, 4); err != context.Canceled {
+		t.Fatal("want cancellation got", err)
+	}
+	if len(parallel.components) == 0 {
+		t.Fatal("want the components of the labeled band kept")
+	}
+	for k, c := range parallel.components {
+		want, ok := first[c.points[0]]
+		if !ok || len(want.points) != len(c.points) || serial.key(want) != parallel.key(c) {
+			t.Fatalf("component %d was not finished", k)
+		}
+	}
+}
+
+func BenchmarkScan(b *testing.B) {
+	grid := randomGrid(rand.New(rand.NewSource(3)), 1000, 1000, 1, 0.5)
+	for _, parallel := range []bool{false, true} {
+		b.Run("parallel="+strconv.FormatBool(parallel), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := NewWithOptions(grid, Options{Parallel: parallel}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package search
+
+// Result summarizes a search in a form suited to encoding as JSON.
+type Result struct {
+	Rows       int           `json:"rows"`
+	Cols       int           `json:"cols"`
+	Components int           `json:"components"`
+	Shapes     []ShapeResult `json:"shapes"`
+	// Partial is true when the search was stopped before it scanned the whole grid.
+	Partial bool `json:"partial,omitempty"`
+}
+
+// ShapeResult describes one unique shape.
+type ShapeResult struct {
+	Value  int `json:"value"`
+	Count  int `json:"count"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+	Holes  int `json:"holes"`
+	// AroundX and AroundY are true for a shape on a torus that joins up with itself all the way around the grid,
+	// left to right or top to bottom. Its cells are one turn of it in that direction, cut in the same place
+	// wherever it lies.
+	AroundX bool `json:"aroundX,omitempty"`
+	AroundY bool `json:"aroundY,omitempty"`
+	// Cells are the column and row of each cell of the first occurrence, measured from its upper left corner. On a
+	// hex lattice they are axial coordinates instead. On a triangular lattice the first column is left empty when
+	// needed so that each cell points the same way as it does on the grid.
+	Cells [][2]int `json:"cells"`
+}
+
+// Result returns the unique shapes with the number of times each occurs.
+func (s state) Result() Result {
+	r := Result{
+		Rows:       s.rows,
+		Cols:       s.cols,
+		Components: s.total(),
+		Shapes:     []ShapeResult{},
+		Partial:    s.partial,
+	}
+	for i, shp := range s.shapes {
+		sr := ShapeResult{Value: shp.value, Count: s.counts[i], Holes: len(shp.holes)}
+		sr.Cells, sr.Width, sr.Height = s.cells(shp)
+		sr.AroundX, sr.AroundY = s.around(shp)
+		r.Shapes = append(r.Shapes, sr)
+	}
+	return r
+}
+
+// cells returns the cells of a shape as ShapeResult gives them, laid out as they are drawn, with the width and
+// height they span.
+func (s state) cells(shp shape) (cells [][2]int, width, height int) {
+	ps, shift := s.layout(shp), 0
+	switch s.opts.Lattice {
+	case Hex:
+		ps = axial(ps)
+	case Triangle:
+		if !pointsUp(origin(ps)) {
+			shift = 1
+		}
+	}
+	for _, p := range normalize(ps) {
+		p.x += shift
+		cells = append(cells, [2]int{p.x, p.y})
+		if p.x+1 > width {
+			width = p.x + 1
+		}
+		if p.y+1 > height {
+			height = p.y + 1
+		}
+	}
+	return cells, width, height
+}
+
+// total is the number of components found, including duplicates.
+func (s state) total() int {
+	var n int
+	for _, c := range s.counts {
+		n += c
+	}
+	return n
+}
+
+// Keys returns a key for each unique shape, in the order of Result. Searches with the same equivalence, lattice,
+// label and hole options give duplicate shapes the same key, whatever grids they searched.
+func (s state) Keys() []string {
+	keys := make([]string, len(s.shapes))
+	for i, shp := range s.shapes {
+		keys[i] = s.key(shp)
+	}
+	return keys
+}
+
+// maxLabelCells is the most cells Labels gives a label, so that a huge sparse grid is never held cell by cell.
+const maxLabelCells = 1 << 24
+
+// Labels returns a grid the size of the one searched, with each cell of a shape set to one more than the index of
+// its unique shape in Result and every other cell zero. It is nil when no shapes were kept cell by cell, as for a
+// stream, and when the grid has more than maxLabelCells cells.
+func (s state) Labels() [][]int {
+	if len(s.components) == 0 || s.rows > maxLabelCells/s.cols {
+		return nil
+	}
+	labels := make([][]int, s.rows)
+	for y := range labels {
+		labels[y] = make([]int, s.cols)
+	}
+	for _, c := range s.components {
+		i := s.index[s.key(c)] + 1
+		for _, p := range c.points {
+			t := p.transform(s.rows, s.cols)
+			labels[t.y][t.x] = i
+		}
+	}
+	return labels
+}
@@ -0,0 +1,89 @@
+package search
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestKeys(t *testing.T) {
+	grid := [][]int{
+		{1, 1, 0, 0, 1},
+		{0, 0, 0, 0, 1},
+		{1, 0, 2, 0, 0},
+		{1, 0, 2, 2, 0},
+	}
+	tt := []struct {
+		pattern [][]int
+		opts    Options
+		want    int
+	}{
+		// the first shape of the pattern is the one looked for among the shapes of the grid
+		{pattern: [][]int{{1, 1}}, opts: Options{Topology: Plane}, want: 1},
+		{pattern: [][]int{{1, 1}}, opts: Options{Topology: Plane, Equivalence: Rotation}, want: 3},
+		{pattern: [][]int{{2, 2}, {2, 0}}, opts: Options{Topology: Plane, Equivalence: Rotation}, want: 1},
+		{pattern: [][]int{{1, 1}, {1, 0}}, opts: Options{Topology: Plane, Equivalence: Rotation}, want: 0},
+		{pattern: [][]int{{1, 1}, {1, 0}}, opts: Options{Topology: Plane, Equivalence: Rotation, AcrossValues: true}, want: 1},
+	}
+	for i, tc := range tt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			s, err := NewWithOptions(grid, tc.opts)
+			if err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			p, err := NewWithOptions(tc.pattern, tc.opts)
+			if err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			var got int
+			for j, k := range s.Keys() {
+				if k == p.Keys()[0] {
+					got += s.counts[j]
+				}
+			}
+			if got != tc.want {
+				t.Fatalf("want %d matches got %d", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestLabels(t *testing.T) {
+	s, err := NewWithOptions([][]int{
+		{1, 1, 0, 1},
+		{0, 0, 0, 0},
+		{2, 0, 1, 1},
+		{0, 0, 0, 0},
+	}, Options{})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	// the first shape wraps from the last column to the first
+	want := [][]int{
+		{1, 1, 0, 1},
+		{0, 0, 0, 0},
+		{2, 0, 3, 3},
+		{0, 0, 0, 0},
+	}
+	if got := s.Labels(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %v got %v", want, got)
+	}
+	s, err = NewStream(context.Background(), strings.NewReader("1 0\n"), Options{})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if s.Labels() != nil {
+		t.Fatal("want no labels for a stream")
+	}
+	sp := NewSparse(1<<20, 1<<20)
+	sp.Set(5, 5, 1)
+	s, err = NewFromGrid(context.Background(), sp, Options{})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if s.Labels() != nil {
+		t.Fatal("want no labels for a grid too large to label cell by cell")
+	}
+}
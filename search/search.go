@@ -1,9 +1,12 @@
 package search
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"runtime"
 	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -13,6 +16,7 @@ func (se stateError) Error() string { return string(se) }
 
 const errorNoRows = stateError("no rows in grid")
 const errorNoCols = stateError("no columns in grid")
+const errorRagged = stateError("rows in grid differ in length")
 
 const (
 	up direction = iota
@@ -20,108 +24,425 @@ const (
 	left
 	right
 
-	set     int = 1
-	unset   int = 0
-	visited int = 2
+	set   int = 1
+	unset int = 0
 
 	leftPadding = "    "
+
+	// checkEvery is how many cells a search visits between checks for cancellation.
+	checkEvery = 4096
 )
 
+// New finds the unique shapes in a grid of ones and zeros, or more generally of integer labels on a zero background.
 func New(g [][]int) (*state, error) {
-	rows := len(g)
+	return NewWithOptions(g, Options{})
+}
+
+// NewWithOptions finds the unique shapes in a grid of integer labels.
+func NewWithOptions(g [][]int, opts Options) (*state, error) {
+	return NewWithContext(context.Background(), g, opts)
+}
+
+// NewWithContext finds the unique shapes in a grid of integer labels, stopping early if ctx is done. A search that
+// stops early returns the shapes completed so far along with the context's error. Holes are only found once the
+// whole grid has been scanned, so the shapes of an unfinished search have none.
+func NewWithContext(ctx context.Context, g [][]int, opts Options) (*state, error) {
+	return NewFromGrid(ctx, Dense(g), opts)
+}
+
+// Progress reports how far a search has got.
+type Progress struct {
+	// RowsScanned counts the rows of the grid that have been scanned for shapes.
+	RowsScanned int
+	// Rows is the number of rows in the grid.
+	Rows int
+	// Components counts the shapes found so far, including duplicates.
+	Components int
+}
+
+// NewRunes finds the unique shapes in a grid given as one string per row, where each rune is a label. The
+// background is given as a rune in opts, for example Options{Background: '.'}.
+func NewRunes(rows []string, opts Options) (*state, error) {
+	g, err := Runes(rows)
+	if err != nil {
+		return nil, err
+	}
+	opts.runes = true
+	return NewWithOptions(g, opts)
+}
+
+// Runes converts one string per row into a grid of rune labels.
+func Runes(rows []string) ([][]int, error) {
+	var g [][]int
+	for _, r := range rows {
+		var row []int
+		for _, c := range r {
+			row = append(row, int(c))
+		}
+		if len(g) > 0 && len(row) != len(g[0]) {
+			return nil, errorRagged
+		}
+		g = append(g, row)
+	}
+	return g, nil
+}
+
+// NewFromGrid finds the unique shapes in any grid, stopping early if ctx is done. Grids other than Dense are
+// searched with the default background. Sparse grids are always searched by a single goroutine, and only look for
+// holes near each shape.
+func NewFromGrid(ctx context.Context, g Grid, opts Options) (*state, error) {
+	if d, ok := g.(Dense); ok {
+		if err := d.check(); err != nil {
+			return nil, err
+		}
+	} else {
+		opts.Background = unset
+	}
+	if len(opts.Preprocess) > 0 {
+		d, ok := g.(Dense)
+		if !ok {
+			return nil, errorMorphGrid
+		}
+		pg, err := opts.Preprocess.Apply(ctx, d, opts)
+		if err != nil && err == ctx.Err() {
+			// Stopped before the search began, so nothing has been found yet.
+			opts.Preprocess = nil
+			st, serr := newGridState(g, opts)
+			if serr != nil {
+				return nil, serr
+			}
+			st.partial = true
+			return st, err
+		}
+		if err != nil {
+			return nil, err
+		}
+		g, opts.Preprocess = Dense(pg), nil
+	}
+	st, err := newGridState(g, opts)
+	if err != nil {
+		return nil, err
+	}
+	err = st.findShapes(ctx)
+	st.partial = err != nil
+	return st, err
+}
+
+func newState(g [][]int, opts Options) (*state, error) {
+	if err := Dense(g).check(); err != nil {
+		return nil, err
+	}
+	return newGridState(Dense(g), opts)
+}
+
+func newGridState(g Grid, opts Options) (*state, error) {
+	rows, cols := g.Rows(), g.Cols()
 	if rows == 0 {
 		return nil, errorNoRows
 	}
-	cols := len(g[0])
 	if cols == 0 {
 		return nil, errorNoCols
 	}
-	st := state{
-		grid:   g,
-		shapes: nil,
-		rows:   rows,
-		cols:   cols,
+	if err := opts.checkSize(rows, cols); err != nil {
+		return nil, err
+	}
+	st := &state{
+		grid:  g,
+		index: make(map[string]int),
+		rows:  rows,
+		cols:  cols,
+		opts:  opts,
 	}
-	st.findShapes()
-	return &st, nil
+	switch {
+	case st.sparse():
+		st.seen = make(cellSet)
+	case opts.Parallel:
+		st.seen = newFlags(rows, cols)
+	default:
+		st.seen = NewBits(rows, cols)
+	}
+	return st, nil
 }
 
 type state struct {
-	grid       [][]int
+	grid       Grid
+	seen       marks
+	components []shape
 	shapes     []shape
+	// counts holds the number of components matching each unique shape.
+	counts     []int
+	index      map[string]int
 	rows, cols int
+	opts       Options
+	// origin is where the first cell of the grid lies on an unbounded plane.
+	origin point
+	// partial is true when the search stopped before scanning the whole grid.
+	partial bool
 }
 
+// Print writes each unique shape, grouped by label when the grid holds more than one.
 func (s state) Print(w io.Writer) {
+	s.printEach(w, func(shp shape) {
+		width := s.draw(w, shp)
+		fmt.Fprintln(w, strings.Repeat("-", width+len(leftPadding)))
+	})
+}
+
+// printEach calls print for each unique shape, writing a heading before each group of labels.
+func (s state) printEach(w io.Writer, print func(shape)) {
+	values := s.values()
+	for _, v := range values {
+		if len(values) > 1 {
+			fmt.Fprintln(w, s.label(v)+":")
+		}
+		for _, shp := range s.shapes {
+			if s.opts.AcrossValues || shp.value == v {
+				print(shp)
+			}
+		}
+	}
+}
+
+// values returns the distinct labels of the unique shapes in ascending order. When shapes are deduplicated
+// across labels there is a single group.
+func (s state) values() []int {
+	if s.opts.AcrossValues {
+		if len(s.shapes) == 0 {
+			return nil
+		}
+		return []int{s.shapes[0].value}
+	}
+	var values []int
+	have := make(map[int]bool)
 	for _, shp := range s.shapes {
-		shp.print(w, s.rows, s.cols)
+		if !have[shp.value] {
+			have[shp.value] = true
+			values = append(values, shp.value)
+		}
 	}
+	sort.Ints(values)
+	return values
 }
 
-func (s *state) findShape(p point) *shape {
-	var result *shape
-	if !s.visited(p) {
-		if s.visit(p) == set {
-			result = new(shape)
-			result.points = append(result.points, p)
-			for _, dir := range []direction{up, right, down, left} {
-				children := s.findShape(nextPoint(p, dir))
-				if children != nil {
-					result.points = append(result.points, children.points...)
-				}
+func (s state) label(v int) string {
+	if s.opts.runes {
+		return fmt.Sprintf("value %q", rune(v))
+	}
+	return fmt.Sprintf("value %d", v)
+}
+
+// findShape collects the cells connected to start that share its value, in the order a depth first search visits
+// them. If ctx is done part way through the shape is abandoned.
+func (s *state) findShape(ctx context.Context, start point, value int) (*shape, error) {
+	s.visit(start)
+	cells, err := flood(ctx, s, 2, []int{start.x, start.y}, value)
+	if err != nil {
+		return nil, err
+	}
+	return &shape{value: value, points: points(cells)}, nil
+}
+
+// squareSteps are the steps to the neighbors of a cell of a square lattice for each connectivity, in the order
+// neighbors gives them.
+var squareSteps = [][][2]int{
+	{{0, -1}, {1, 0}, {0, 1}, {-1, 0}},
+	{{0, -1}, {1, 0}, {0, 1}, {-1, 0}, {1, -1}, {1, 1}, {-1, 1}, {-1, -1}},
+}
+
+// joined appends the neighbors of a cell as coordinates, for flood.
+func (s *state) joined(ns, c []int) []int {
+	if s.opts.Lattice == Square {
+		steps := squareSteps[s.opts.Connectivity]
+		if ns == nil {
+			ns = make([]int, 0, 2*len(steps))
+		}
+		for _, d := range steps {
+			x, y := c[0]+d[0], c[1]+d[1]
+			if s.opts.Topology == Plane && (x < 0 || x >= s.cols || y < 0 || y >= s.rows) {
+				continue
 			}
+			ns = append(ns, x, y)
 		}
+		return ns
+	}
+	for _, n := range s.neighbors(point{c[0], c[1]}) {
+		ns = append(ns, n.x, n.y)
 	}
+	return ns
+}
 
-	return result
+// take claims a cell for flood. The value is checked first so that a search never looks at cells belonging to
+// other shapes, which may be being searched at the same time.
+func (s *state) take(c []int, value int) bool {
+	p := point{c[0], c[1]}
+	if s.value(p) != value || s.visited(p) {
+		return false
+	}
+	s.visit(p)
+	return true
 }
 
-func (s *state) findShapes() {
-	for row := 0; row < s.rows; row++ {
-		for col := 0; col < s.cols; col++ {
-			shape := s.findShape(getPoint(col, row))
-			if shape != nil {
-				if !s.hasShape(*shape) {
-					s.shapes = append(s.shapes, *shape)
-				}
+func (s *state) findShapes(ctx context.Context) error {
+	defer s.dedupe()
+	var err error
+	switch {
+	case s.opts.Parallel && !s.sparse():
+		err = s.scanParallel(ctx, runtime.GOMAXPROCS(0))
+	default:
+		err = s.scan(ctx)
+	}
+	if err != nil {
+		return err
+	}
+	return s.findHoles(ctx)
+}
+
+// scan finds each component by searching from its first cell in row order, skipping the cells the grid knows to be
+// empty.
+func (s *state) scan(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	row := 0
+	x, y, ok := s.next(0, 0)
+	for ; ok; x, y, ok = s.next(x+1, y) {
+		if y > row {
+			s.progress(y)
+			row = y
+			if err := ctx.Err(); err != nil {
+				return err
 			}
 		}
+		p := getPoint(x, y)
+		v := s.value(p)
+		if v == s.opts.Background || s.visited(p) {
+			continue
+		}
+		shp, err := s.findShape(ctx, p, v)
+		if err != nil {
+			return err
+		}
+		s.components = append(s.components, *shp)
 	}
+	s.progress(s.rows)
+	return nil
 }
 
-func (s state) hasShape(shp shape) bool {
-	for _, comp := range s.shapes {
-		if comp.match(shp) {
-			return true
+// next returns the first cell at or after column x of row y that may start a shape. A bit packed grid passes over
+// the cells already visited a word at a time, along with the empty ones.
+func (s *state) next(x, y int) (int, int, bool) {
+	if b, ok := s.grid.(*Bits); ok {
+		if seen, ok := s.seen.(*Bits); ok {
+			return b.next(x, y, seen)
 		}
 	}
-	return false
+	return s.grid.Next(x, y)
 }
 
-func (s state) isShapePart(p point) bool {
-	trns := p.transform(s.rows, s.cols)
-	if s.grid[trns.x][trns.y] == set {
-		return true
+// progress reports that the rows before row have been scanned.
+func (s state) progress(row int) {
+	if s.opts.Progress != nil {
+		s.opts.Progress(Progress{RowsScanned: row, Rows: s.rows, Components: len(s.components)})
 	}
-	return false
 }
 
-func (s *state) visit(p point) int {
+// sparse reports whether the grid only holds its set cells, so nothing can be kept for every cell.
+func (s state) sparse() bool {
+	_, ok := s.grid.(*Sparse)
+	return ok
+}
+
+// dedupe drops the components the filter does not keep and keeps the first of each equivalence class left as a
+// unique shape.
+func (s *state) dedupe() {
+	if len(s.opts.Filter) > 0 {
+		var kept []shape
+		for _, c := range s.components {
+			if s.keep(c) {
+				kept = append(kept, c)
+			}
+		}
+		s.components = kept
+	}
+	for _, c := range s.components {
+		k := s.key(c)
+		i, ok := s.index[k]
+		if !ok {
+			i = len(s.shapes)
+			s.index[k] = i
+			s.shapes = append(s.shapes, c)
+			s.counts = append(s.counts, 0)
+		}
+		s.counts[i]++
+	}
+}
+
+// neighbors returns the cells connected to p, leaving out those past the edge of a plane.
+func (s state) neighbors(p point) []point {
+	switch s.opts.Lattice {
+	case Hex:
+		return s.onGrid(hexNeighbors(p))
+	case Triangle:
+		return s.onGrid(triangleNeighbors(p))
+	}
+	ns := []point{nextPoint(p, up), nextPoint(p, right), nextPoint(p, down), nextPoint(p, left)}
+	if s.opts.Connectivity == EightWay {
+		ns = append(ns, nextPoint(ns[0], right), nextPoint(ns[2], right), nextPoint(ns[2], left), nextPoint(ns[0], left))
+	}
+	return s.onGrid(ns)
+}
+
+// onGrid filters out points past the edge of the grid when it is a plane. On a torus every point is on the grid.
+func (s state) onGrid(ps []point) []point {
+	if s.opts.Topology != Plane {
+		return ps
+	}
+	var on []point
+	for _, p := range ps {
+		if p.x >= 0 && p.x < s.cols && p.y >= 0 && p.y < s.rows {
+			on = append(on, p)
+		}
+	}
+	return on
+}
+
+func (s state) hasShape(shp shape) bool {
+	_, ok := s.index[s.key(shp)]
+	return ok
+}
+
+// key identifies the equivalence class of a shape. Labels are part of the key unless shapes are compared across
+// labels.
+func (s state) key(shp shape) string {
+	if s.opts.FillHoles {
+		shp.points = shp.filled()
+	}
+	if s.opts.AcrossValues {
+		return s.canonical(shp)
+	}
+	return fmt.Sprintf("%d:%s", shp.value, s.canonical(shp))
+}
+
+func (s state) isShapePart(p point) bool {
+	return s.value(p) != s.opts.Background
+}
+
+func (s state) value(p point) int {
 	t := p.transform(s.rows, s.cols)
-	old := s.grid[t.y][t.x]
-	if old == visited {
+	return s.grid.Value(t.x, t.y)
+}
+
+func (s *state) visit(p point) {
+	t := p.transform(s.rows, s.cols)
+	if s.seen.marked(t) {
 		panic(fmt.Sprint("original", p, "transformed", t))
 	}
-	s.grid[t.y][t.x] = visited
-	return old
+	s.seen.mark(t)
 }
 
 func (s state) visited(p point) bool {
 	t := p.transform(s.rows, s.cols)
-	if s.grid[t.y][t.x] == visited {
-		return true
-	}
-	return false
+	return s.seen.marked(t)
 }
 
 type direction int
@@ -165,76 +486,208 @@ func (p point) String() string {
 	return fmt.Sprintf("point(x:%d, y:%d)", p.x, p.y)
 }
 
-type sorter struct {
-	points     []point
-	rows, cols int
+type shape struct {
+	points []point
+	value  int
+	// holes are the background regions the shape encloses, in the same coordinates as points.
+	holes [][]point
 }
 
-func (s *sorter) Len() int      { return len(s.points) }
-func (s *sorter) Swap(i, j int) { s.points[i], s.points[j] = s.points[j], s.points[i] }
-func (s *sorter) Less(i, j int) bool {
-	return s.points[i].index(s.rows, s.cols) < s.points[j].index(s.rows, s.cols)
+func (s shape) String() string {
+	var resp string
+	for i := 0; i < len(s.points)-1; i++ {
+		if resp != "" {
+			resp += ", "
+		}
+		resp += getDirection(s.points[i], s.points[i+1]).String()
+	}
+	return resp
 }
 
-type rowState []int
+// draw renders the shape as its lattice is drawn, with its hole count, and returns the width drawn.
+func (s state) draw(w io.Writer, shp shape) int {
+	var width int
+	g := s.opts.Glyphs.orDefault()
+	ps := s.layout(shp)
+	switch s.opts.Lattice {
+	case Hex:
+		width = drawHex(w, ps, g)
+	case Triangle:
+		width = drawTriangles(w, ps, g)
+	default:
+		width = drawCoords(w, coords(ps), g)
+	}
+	shp.printHoles(w)
+	return width
+}
 
-func (r rowState) empty() bool {
-	for _, p := range r {
-		if p == set {
-			return false
+// layout returns the cells of a shape as they were found, so that one crossing an edge of a torus lies in one
+// piece. In a direction that the shape wraps all the way around there is no such piece, and its cells are put back
+// on the grid instead, one turn of it, cut in the same place wherever it lies on the grid.
+func (s state) layout(shp shape) []point {
+	x, y := s.around(shp)
+	if !x && !y {
+		return shp.points
+	}
+	var best []point
+	var bestKey string
+	for _, ps := range s.windings(shp.points, x, y) {
+		if k := (shape{points: ps}).canonical(Translation, s.opts.Lattice); best == nil || k < bestKey {
+			best, bestKey = ps, k
 		}
 	}
-	return true
+	return best
 }
 
-func (r rowState) print(w io.Writer) {
-	fmt.Fprint(w, leftPadding)
-	for _, p := range r {
-		switch p {
-		case set:
-			fmt.Fprint(w, "X")
-		case unset:
-			fmt.Fprint(w, " ")
+// laidOut returns the shape with its cells where layout puts them, and each hole moved along with a cell beside it.
+// A hole that the cut of a shape wrapping around runs through is no longer enclosed, and is left out.
+func (s state) laidOut(shp shape) shape {
+	ps := s.layout(shp)
+	if len(shp.holes) == 0 {
+		shp.points = ps
+		return shp
+	}
+	// layout keeps the cells in order, so each is paired with where it was found.
+	moves := make(map[point]point, len(ps))
+	laid := make(map[point]bool, len(ps))
+	for i, p := range shp.points {
+		moves[p] = point{ps[i].x - p.x, ps[i].y - p.y}
+		laid[ps[i]] = true
+	}
+	beside := func(h []point) point {
+		for _, p := range h {
+			for _, n := range s.neighbors(p) {
+				if m, ok := moves[n]; ok {
+					return m
+				}
+			}
+		}
+		return point{}
+	}
+	var holes [][]point
+	for _, h := range shp.holes {
+		d := beside(h)
+		hole := make(map[point]bool, len(h))
+		for _, p := range h {
+			hole[point{p.x + d.x, p.y + d.y}] = true
+		}
+		enclosed := true
+		for p := range hole {
+			for _, n := range s.backgroundNeighbors(p) {
+				enclosed = enclosed && (hole[n] || laid[n])
+			}
+		}
+		if !enclosed {
+			continue
+		}
+		moved := make([]point, len(h))
+		for i, p := range h {
+			moved[i] = point{p.x + d.x, p.y + d.y}
 		}
+		holes = append(holes, moved)
 	}
-	fmt.Fprintln(w)
+	shp.points, shp.holes = ps, holes
+	return shp
 }
 
-type shape struct {
-	points []point
+// placed returns the cells of a shape where they lie on the grid. They are kept as they were found, so one crossing
+// an edge of a torus lies in one piece past it, except in a direction the shape wraps all the way around, where they
+// are put back on the grid.
+func (s state) placed(shp shape) []point {
+	x, y := s.around(shp)
+	if !x && !y {
+		return shp.points
+	}
+	ps := make([]point, len(shp.points))
+	for i, p := range shp.points {
+		if x {
+			p.x = wrap(p.x, s.cols)
+		}
+		if y {
+			p.y = wrap(p.y, s.rows)
+		}
+		ps[i] = p
+	}
+	return ps
 }
 
-func (s shape) String() string {
-	var resp string
-	for i := 0; i < len(s.points)-1; i++ {
-		if resp != "" {
-			resp += ", "
+// canonical is the canonical key of a shape. One that wraps all the way around the torus is wound after each move
+// of the equivalence on a square lattice, and before them on the others, so that a copy of it anywhere on the grid
+// has the same key.
+func (s state) canonical(shp shape) string {
+	x, y := s.around(shp)
+	switch {
+	case !x && !y:
+		return shp.canonical(s.opts.Equivalence, s.opts.Lattice)
+	case s.opts.Lattice == Square:
+		return canonicalCoords(coords(shp.points), squareTurns[s.opts.Equivalence], []int{s.cols, s.rows}, []bool{x, y})
+	}
+	var best string
+	for _, ps := range s.windings(shp.points, x, y) {
+		if k := (shape{points: ps}).canonical(s.opts.Equivalence, s.opts.Lattice); best == "" || k < best {
+			best = k
 		}
-		resp += getDirection(s.points[i], s.points[i+1]).String()
 	}
-	return resp
+	return best
 }
 
-func (s shape) print(w io.Writer, rows, cols int) {
-	transformedPoints, newRows, newCols := transform(s.points, rows, cols)
-
-	byIndex := sorter{
-		points: transformedPoints,
-		rows:   newRows,
-		cols:   newCols,
+// windings returns the ways wind puts cells that wrap around the torus back on the grid. Hex and triangle cells
+// only slide onto the same grid cells by whole steps of two rows, or two columns for triangles, and otherwise are
+// cut differently, so the cells slid by a single row, or a row and a column, are wound as well.
+func (s state) windings(ps []point, x, y bool) [][]point {
+	size, step, around := []int{s.cols, s.rows}, []int{1, 1}, []bool{x, y}
+	var slid func(p point) point
+	switch s.opts.Lattice {
+	case Hex:
+		step[1] = 2
+		slid = func(p point) point { return point{p.x + p.y&1, p.y + 1} }
+	case Triangle:
+		step = []int{2, 2}
+		slid = func(p point) point { return point{p.x + 1, p.y + 1} }
 	}
-	sort.Sort(&byIndex)
+	ws := [][]point{fromCoords(wind(coords(ps), size, step, around))}
+	if slid != nil {
+		moved := make([]point, len(ps))
+		for i, p := range ps {
+			moved[i] = slid(p)
+		}
+		ws = append(ws, fromCoords(wind(coords(moved), size, step, around)))
+	}
+	return ws
+}
 
-	for row := 0; row < newRows; row++ {
-		rowPoints := make(rowState, newCols)
-		for _, p := range byIndex.points {
-			if p.y == row {
-				rowPoints[p.x] = set
+// around reports whether a shape on a torus joins up with itself all the way around the grid, left to right or
+// top to bottom. Its cells are unrolled as they were found, so two cells that are neighbors on the grid but were
+// not found beside each other are a turn apart. A cell that is its own neighbor, on a grid one cell wide or high,
+// does not count.
+func (s state) around(shp shape) (x, y bool) {
+	if s.opts.Topology == Plane {
+		return false, false
+	}
+	// Neighbors are at most a column apart, so a shape narrower than the grid cannot reach around it.
+	if lx, ux, ly, uy := shapeDimensions(shp.points); ux-lx+1 < s.cols && uy-ly+1 < s.rows {
+		return false, false
+	}
+	found := make(map[point]point, len(shp.points))
+	for _, p := range shp.points {
+		found[p.transform(s.rows, s.cols)] = p
+	}
+	for _, p := range shp.points {
+		for _, n := range s.neighbors(p) {
+			f, ok := found[n.transform(s.rows, s.cols)]
+			if ok && f != p {
+				x = x || f.x != n.x
+				y = y || f.y != n.y
 			}
 		}
-		rowPoints.print(w)
 	}
-	fmt.Fprintln(w, strings.Repeat("-", newCols+len(leftPadding)))
+	return x, y
+}
+
+func (s shape) printHoles(w io.Writer) {
+	if len(s.holes) > 0 {
+		fmt.Fprintf(w, "%sholes: %d\n", leftPadding, len(s.holes))
+	}
 }
 
 func getDirection(b, e point) direction {
@@ -261,17 +714,53 @@ func (s shape) match(v shape) bool {
 	if len(s.points) != len(v.points) {
 		return false
 	}
-	if len(v.points) == 1 {
-		return true
+	return s.key() == v.key()
+}
+
+// key is the shape's cell layout with its origin moved to the upper left, independent of the order the cells
+// were found in.
+func (s shape) key() string {
+	var b []byte
+	for _, p := range normalize(s.points) {
+		b = strconv.AppendInt(b, int64(p.x), 10)
+		b = append(b, ',')
+		b = strconv.AppendInt(b, int64(p.y), 10)
+		b = append(b, ';')
 	}
-	for i := 0; i < len(s.points)-1; i++ {
-		d1 := getDirection(s.points[i], s.points[i+1])
-		d2 := getDirection(v.points[i], v.points[i+1])
-		if d1 != d2 {
-			return false
+	return string(b)
+}
+
+// normalize translates points so the smallest x and y are zero and sorts them by row, then column.
+func normalize(ps []point) []point {
+	if len(ps) == 0 {
+		return nil
+	}
+	o := origin(ps)
+	normal := make([]point, len(ps))
+	for i, p := range ps {
+		normal[i] = point{p.x - o.x, p.y - o.y}
+	}
+	sort.Slice(normal, func(i, j int) bool {
+		if normal[i].y != normal[j].y {
+			return normal[i].y < normal[j].y
+		}
+		return normal[i].x < normal[j].x
+	})
+	return normal
+}
+
+// origin returns the smallest x and y among points.
+func origin(ps []point) point {
+	o := ps[0]
+	for _, p := range ps {
+		if p.x < o.x {
+			o.x = p.x
+		}
+		if p.y < o.y {
+			o.y = p.y
 		}
 	}
-	return true
+	return o
 }
 
 func nextPoint(curr point, d direction) point {
@@ -288,8 +777,9 @@ func nextPoint(curr point, d direction) point {
 	panic("nextPoint is f'd")
 }
 
-func shapeDimensions(ps []point, rows, cols int) (lx, ux, ly, uy int) {
-	lx, ux, ly, uy = cols, 0, rows, 0
+// shapeDimensions returns the smallest and largest x and y among points.
+func shapeDimensions(ps []point) (lx, ux, ly, uy int) {
+	lx, ux, ly, uy = ps[0].x, ps[0].x, ps[0].y, ps[0].y
 	for _, p := range ps {
 		if p.x < lx {
 			lx = p.x
@@ -307,22 +797,6 @@ func shapeDimensions(ps []point, rows, cols int) (lx, ux, ly, uy int) {
 	return
 }
 
-func transform(ps []point, rows, cols int) ([]point, int, int) {
-	var transformed []point
-
-	for _, p := range ps {
-		tp := p.transform(rows, cols)
-		transformed = append(transformed, tp)
-	}
-	lowX, highX, lowY, highY := shapeDimensions(transformed, rows, cols)
-
-	for i := 0; i < len(transformed); i++ {
-		transformed[i].x -= lowX
-		transformed[i].y -= lowY
-	}
-	return transformed, highY - lowY + 1, highX - lowX + 1
-}
-
 func wrap(i, dim int) int {
 	if i >= 0 {
 		return i % dim
@@ -2,6 +2,8 @@ package search
 
 import (
 	"bytes"
+	"context"
+	"reflect"
 	"strconv"
 	"testing"
 )
@@ -59,10 +61,9 @@ func TestWasSeen(t *testing.T) {
 				{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
 				{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
 			}
-			s := state{
-				grid: grid,
-				rows: len(grid),
-				cols: len(grid[0]),
+			s, err := newState(grid, Options{})
+			if err != nil {
+				t.Fatal("unexpected error", err)
 			}
 			if tc.mark {
 				s.visit(tc.visited)
@@ -146,8 +147,8 @@ func TestShapesMatch(t *testing.T) {
 	for i, tc := range tt {
 		t.Run(strconv.Itoa(i), func(t *testing.T) {
 
-			shp := shape{tc.u}
-			comp := shape{tc.v}
+			shp := shape{points: tc.u}
+			comp := shape{points: tc.v}
 			if shp.match(comp) != tc.match {
 				t.Logf("-> %q", shp)
 				t.Logf("-> %q", comp)
@@ -218,7 +219,8 @@ func TestIntegration(t *testing.T) {
 				{0, 0, 0, 0, 0},
 				{0, 1, 1, 0, 0},
 			},
-			want: "    XXX\n    X X\n    XXX\n       \n    XX \n-------\n",
+			// the domino joins the ring across the bottom edge and is drawn above it, in one piece
+			want: "    XX \n    XXX\n    X X\n    XXX\n    holes: 1\n-------\n",
 		},
 		{
 			grid: [][]int{
@@ -230,7 +232,7 @@ func TestIntegration(t *testing.T) {
 				{0, 1, 0, 0, 0},
 				{0, 1, 0, 0, 0},
 			},
-			want: "    XX\n    X \n    X \n      \n    XX\n    X \n    X \n------\n",
+			want: "    XX\n    X \n    X \n    XX\n    X \n    X \n------\n",
 		},
 		{
 			grid: [][]int{
@@ -238,7 +240,9 @@ func TestIntegration(t *testing.T) {
 				{1, 1, 1, 1},
 				{0, 0, 0, 1},
 			},
-			want: "       X\n    XXXX\n       X\n--------\n",
+			// the shape wraps all the way around both ways, leaving the rest of the grid enclosed, and is cut to
+			// start with its full row whichever rows of the grid it lies on
+			want: "    XXXX\n       X\n       X\n    holes: 1\n--------\n",
 		},
 	}
 
@@ -258,3 +262,292 @@ func TestIntegration(t *testing.T) {
 		})
 	}
 }
+
+func TestValues(t *testing.T) {
+	tt := []struct {
+		grid [][]int
+		opts Options
+		want string
+	}{
+		{
+			grid: [][]int{
+				{2, 2, 0, 3, 0},
+				{0, 0, 0, 3, 0},
+				{0, 0, 0, 0, 0},
+				{0, 2, 2, 0, 0},
+				{0, 0, 0, 0, 0},
+			},
+			want: "value 2:\n    XX\n------\nvalue 3:\n    X\n    X\n-----\n",
+		},
+		{
+			grid: [][]int{
+				{2, 2, 0, 3, 0},
+				{0, 0, 0, 3, 0},
+				{0, 0, 0, 0, 0},
+				{0, 3, 3, 0, 0},
+				{0, 0, 0, 0, 0},
+			},
+			opts: Options{AcrossValues: true},
+			want: "    XX\n------\n    X\n    X\n-----\n",
+		},
+		{
+			// adjacent cells with different labels are separate shapes
+			grid: [][]int{
+				{1, 2, 0},
+				{0, 0, 0},
+			},
+			opts: Options{AcrossValues: true},
+			want: "    X\n-----\n",
+		},
+		{
+			grid: [][]int{
+				{5, 5, 5},
+				{5, 1, 5},
+				{5, 5, 5},
+			},
+			opts: Options{Background: 5},
+			want: "    X\n-----\n",
+		},
+	}
+
+	for i, tc := range tt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var w bytes.Buffer
+			s, err := NewWithOptions(tc.grid, tc.opts)
+			if err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			s.Print(&w)
+			if w.String() != tc.want {
+				t.Logf("want %q", tc.want)
+				t.Logf("got  %q", w.String())
+				t.Fatal()
+			}
+		})
+	}
+}
+
+func TestRunes(t *testing.T) {
+	var w bytes.Buffer
+	s, err := NewRunes([]string{
+		"aa.b.",
+		"...b.",
+		".....",
+		"cc...",
+		".....",
+	}, Options{Background: '.'})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	s.Print(&w)
+	want := "value 'a':\n    XX\n------\nvalue 'b':\n    X\n    X\n-----\nvalue 'c':\n    XX\n------\n"
+	if w.String() != want {
+		t.Logf("want %q", want)
+		t.Logf("got  %q", w.String())
+		t.Fatal()
+	}
+
+	if _, err := NewRunes([]string{"ab", "a"}, Options{}); err != errorRagged {
+		t.Fatalf("want %v got %v", errorRagged, err)
+	}
+}
+
+func TestNewWithContext(t *testing.T) {
+	grid := [][]int{
+		{1, 0, 0, 0},
+		{0, 0, 0, 0},
+		{1, 1, 0, 0},
+		{0, 0, 0, 0},
+		{1, 1, 1, 0},
+		{0, 0, 0, 0},
+	}
+
+	var reports []Progress
+	s, err := NewWithContext(context.Background(), grid, Options{Progress: func(p Progress) {
+		reports = append(reports, p)
+	}})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if len(reports) != len(grid) {
+		t.Fatalf("want %d progress reports got %d", len(grid), len(reports))
+	}
+	want := Progress{RowsScanned: 3, Rows: 6, Components: 2}
+	if reports[2] != want {
+		t.Fatalf("want %+v got %+v", want, reports[2])
+	}
+	if len(s.shapes) != 3 || s.Result().Partial {
+		t.Fatalf("want a complete search with 3 shapes got %d", len(s.shapes))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s, err = NewWithContext(ctx, grid, Options{Progress: func(p Progress) {
+		if p.RowsScanned == 3 {
+			cancel()
+		}
+	}})
+	if err != context.Canceled {
+		t.Fatalf("want %v got %v", context.Canceled, err)
+	}
+	if len(s.shapes) != 2 || !s.Result().Partial {
+		t.Fatalf("want a partial search with 2 shapes got %d", len(s.shapes))
+	}
+}
+
+func TestCancelLargeShape(t *testing.T) {
+	grid := make([][]int, 200)
+	for i := range grid {
+		grid[i] = make([]int, 200)
+		for j := range grid[i] {
+			grid[i][j] = 1
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s, err := newState(grid, Options{})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if _, err := s.findShape(ctx, getPoint(0, 0), 1); err != context.Canceled {
+		t.Fatalf("want %v got %v", context.Canceled, err)
+	}
+}
+
+func TestAround(t *testing.T) {
+	tt := []struct {
+		grid       [][]int
+		opts       Options
+		wantX      bool
+		wantY      bool
+		wantWidth  int
+		wantHeight int
+	}{
+		{
+			// crossing an edge is not going all the way around, and the shape is measured in one piece
+			grid:       [][]int{{1, 0, 0, 1}},
+			wantWidth:  2,
+			wantHeight: 1,
+		},
+		{grid: [][]int{{1, 1, 1}}, wantX: true, wantWidth: 3, wantHeight: 1},
+		{grid: [][]int{{1}, {1}, {1}}, wantY: true, wantWidth: 1, wantHeight: 3},
+		{grid: [][]int{{1, 1, 1}}, opts: Options{Topology: Plane}, wantWidth: 3, wantHeight: 1},
+		{grid: [][]int{{1}}, wantWidth: 1, wantHeight: 1},
+		{
+			// on a grid two rows high a column is joined to itself across both edges
+			grid:       [][]int{{1, 0, 0}, {1, 0, 0}},
+			wantY:      true,
+			wantWidth:  1,
+			wantHeight: 2,
+		},
+		{
+			grid:       [][]int{{0, 0, 0, 1}, {1, 1, 1, 1}, {0, 0, 0, 1}},
+			wantX:      true,
+			wantY:      true,
+			wantWidth:  4,
+			wantHeight: 3,
+		},
+	}
+	for i, tc := range tt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			s, err := NewWithOptions(tc.grid, tc.opts)
+			if err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			got := s.Result().Shapes[0]
+			if got.AroundX != tc.wantX || got.AroundY != tc.wantY || got.Width != tc.wantWidth || got.Height != tc.wantHeight {
+				t.Fatalf("want around %v, %v and %d by %d got %+v", tc.wantX, tc.wantY, tc.wantWidth, tc.wantHeight, got)
+			}
+		})
+	}
+}
+
+func TestAroundMoved(t *testing.T) {
+	tt := []struct {
+		grid [][]int
+		opts Options
+	}{
+		{grid: [][]int{{1, 1, 0, 0}, {1, 1, 1, 1}, {0, 0, 0, 0}, {0, 0, 0, 0}, {0, 0, 0, 0}}},
+		{grid: [][]int{{1, 0, 0, 0, 0}, {1, 0, 0, 0, 0}, {1, 1, 0, 0, 0}, {1, 0, 0, 0, 0}}},
+		{
+			grid: [][]int{{1, 1, 0, 0}, {0, 1, 0, 0}, {0, 0, 1, 0}, {0, 0, 0, 1}},
+			opts: Options{Connectivity: EightWay, Equivalence: Rotation},
+		},
+		{grid: [][]int{{1, 1, 1, 1}, {0, 1, 0, 0}, {0, 0, 0, 0}, {0, 0, 0, 0}}, opts: Options{Lattice: Hex}},
+		{grid: [][]int{{1, 0, 0, 0}, {1, 1, 0, 0}, {1, 0, 0, 0}, {1, 0, 0, 0}}, opts: Options{Lattice: Hex}},
+		{grid: [][]int{{1, 1, 1, 1}, {1, 0, 0, 0}, {0, 0, 0, 0}, {0, 0, 0, 0}}, opts: Options{Lattice: Triangle}},
+		{grid: [][]int{{1, 1}, {1, 1}}},
+		{grid: [][]int{{1, 1, 1, 1}, {1, 0, 1, 1}, {1, 1, 1, 1}, {0, 0, 0, 0}}},
+	}
+	for i, tc := range tt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			s, err := NewWithOptions(tc.grid, tc.opts)
+			if err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			want, wantCells := s.Keys()[0], s.Result().Shapes[0].Cells
+			var contours, svg bytes.Buffer
+			s.PrintContours(&contours)
+			if err := s.WriteSVG(&svg); err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			rows, cols := len(tc.grid), len(tc.grid[0])
+			for dy := 0; dy < rows; dy++ {
+				for dx := 0; dx < cols; dx++ {
+					if tc.opts.Lattice == Triangle && (dx+dy)&1 == 1 {
+						continue
+					}
+					moved := make([][]int, rows)
+					for y := range moved {
+						moved[y] = make([]int, cols)
+					}
+					for y, row := range tc.grid {
+						for x, v := range row {
+							p := point{x, y}
+							for i := 0; i < dy; i++ {
+								// a hex cell one row down lies half a cell right or left as its row is odd or even
+								if tc.opts.Lattice == Hex {
+									p.x += p.y & 1
+								}
+								p.y++
+							}
+							p = point{p.x + dx, p.y}.transform(rows, cols)
+							moved[p.y][p.x] = v
+						}
+					}
+					m, err := NewWithOptions(moved, tc.opts)
+					if err != nil {
+						t.Fatal("unexpected error", err)
+					}
+					if r := m.Result(); len(r.Shapes) != 1 || m.Keys()[0] != want || !reflect.DeepEqual(r.Shapes[0].Cells, wantCells) {
+						t.Fatalf("moved by %d, %d: want key %q and cells %v got %q and %+v", dx, dy, want, wantCells, m.Keys(), r.Shapes)
+					}
+					var mc, ms bytes.Buffer
+					m.PrintContours(&mc)
+					if err := m.WriteSVG(&ms); err != nil {
+						t.Fatal("unexpected error", err)
+					}
+					if mc.String() != contours.String() || ms.String() != svg.String() {
+						t.Fatalf("moved by %d, %d: want contours and svg\n%s%s\ngot\n%s%s", dx, dy, &contours, &svg, &mc, &ms)
+					}
+				}
+			}
+		})
+	}
+	// both copies wrap around the grid, cut in different places
+	s, err := New([][]int{
+		{1, 1, 0, 0},
+		{1, 1, 1, 1},
+		{0, 0, 0, 0},
+		{0, 0, 0, 0},
+		{0, 0, 0, 0},
+		{1, 0, 0, 1},
+		{1, 1, 1, 1},
+		{0, 0, 0, 0},
+	})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if r := s.Result(); len(r.Shapes) != 1 || r.Shapes[0].Count != 2 {
+		t.Fatalf("want one shape found twice got %+v", r.Shapes)
+	}
+}
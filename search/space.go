@@ -0,0 +1,737 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	errorNoAxes     = stateError("a space needs at least one axis")
+	errorEmptyAxis  = stateError("every axis of a space needs at least one cell")
+	errorPlaneAxes  = stateError("plane must be given for every axis of the space or none")
+	errorNotVolume  = stateError("meshes can only be written for three dimensional spaces")
+	errorCoordCount = stateError("coordinate has the wrong number of axes")
+)
+
+// Space is a grid of labeled cells with any number of axes: a strip, an image, a volume or a volume through time.
+// A cell is addressed by one coordinate per axis, the first axis varying fastest, so a two dimensional space is
+// indexed by column then row.
+type Space struct {
+	size  []int
+	cells []int
+}
+
+// NewSpace returns a space of the given size along each axis with every cell set to zero.
+func NewSpace(size ...int) *Space {
+	n := 1
+	for _, s := range size {
+		n *= s
+	}
+	if len(size) == 0 || n < 0 {
+		n = 0
+	}
+	return &Space{size: append([]int(nil), size...), cells: make([]int, n)}
+}
+
+// Size returns the number of cells along each axis.
+func (s *Space) Size() []int { return append([]int(nil), s.size...) }
+
+// Value returns the label of the cell at c.
+func (s *Space) Value(c []int) int { return s.cells[s.index(c)] }
+
+// Set labels the cell at c. It panics if the cell is outside the space.
+func (s *Space) Set(c []int, v int) {
+	if len(c) != len(s.size) {
+		panic(errorCoordCount)
+	}
+	for a, x := range c {
+		if x < 0 || x >= s.size[a] {
+			panic(errorOffGrid)
+		}
+	}
+	s.cells[s.index(c)] = v
+}
+
+// index is the position of a cell among all cells of the space.
+func (s *Space) index(c []int) int {
+	i := 0
+	for a := len(c) - 1; a >= 0; a-- {
+		i = i*s.size[a] + c[a]
+	}
+	return i
+}
+
+// coord is the cell at position i among all cells of the space.
+func (s *Space) coord(i int) []int {
+	c := make([]int, len(s.size))
+	for a, n := range s.size {
+		c[a] = i % n
+		i /= n
+	}
+	return c
+}
+
+func (s *Space) check() error {
+	if len(s.size) == 0 {
+		return errorNoAxes
+	}
+	for _, n := range s.size {
+		if n <= 0 {
+			return errorEmptyAxis
+		}
+	}
+	return nil
+}
+
+// Adjacency is the way cells of a space join into shapes.
+type Adjacency int
+
+const (
+	// FaceAdjacent joins cells that share a face, two neighbors along each axis.
+	FaceAdjacent Adjacency = iota
+	// CornerAdjacent joins cells that share a face, an edge or a corner, every cell within one step along each
+	// axis.
+	CornerAdjacent
+)
+
+var adjacencies = []string{"face", "corner"}
+
+func (a Adjacency) String() string { return adjacencies[a] }
+
+// UnmarshalText accepts face or corner.
+func (a *Adjacency) UnmarshalText(b []byte) error {
+	i, err := parseOption(string(b), adjacencies)
+	*a = Adjacency(i)
+	return err
+}
+
+// SpaceOptions controls how a space is searched.
+type SpaceOptions struct {
+	// Background is the cell value treated as empty space.
+	Background int
+	// AcrossValues treats shapes with the same layout as duplicates even when their labels differ.
+	AcrossValues bool
+	// Adjacency decides whether cells touching only at an edge or a corner belong to the same shape.
+	Adjacency Adjacency
+	// Plane ends the space at its faces along each axis it is set for. Axes that are not ended wrap around to the
+	// opposite face. It is either empty, wrapping every axis, or has an entry for each axis.
+	Plane []bool
+	// Equivalence decides which shapes count as duplicates. Rotation allows the turns of the space and Reflection
+	// the turns and mirror images, each mapping axes onto axes.
+	Equivalence Equivalence
+	// Glyphs are the characters Print draws cells with.
+	Glyphs Glyphs
+}
+
+// cluster is a connected set of cells sharing a label, in the order a depth first search found them.
+type cluster struct {
+	cells [][]int
+	value int
+}
+
+type spaceState struct {
+	space *Space
+	// seen marks the cells already searched by their index in the space.
+	seen       []bool
+	steps      [][]int
+	turns      [][]int
+	components []cluster
+	shapes     []cluster
+	counts     []int
+	index      map[string]int
+	opts       SpaceOptions
+	partial    bool
+}
+
+// NewFromSpace finds the unique shapes in a space, stopping early if ctx is done.
+func NewFromSpace(ctx context.Context, sp *Space, opts SpaceOptions) (*spaceState, error) {
+	if err := sp.check(); err != nil {
+		return nil, err
+	}
+	if len(opts.Plane) != 0 && len(opts.Plane) != len(sp.size) {
+		return nil, errorPlaneAxes
+	}
+	st := &spaceState{
+		space: sp,
+		seen:  make([]bool, len(sp.cells)),
+		steps: axisSteps(len(sp.size), opts.Adjacency),
+		turns: axisTurns(len(sp.size), opts.Equivalence),
+		index: make(map[string]int),
+		opts:  opts,
+	}
+	err := st.findShapes(ctx)
+	st.partial = err != nil
+	st.dedupe()
+	return st, err
+}
+
+// Dims returns the number of axes of the space searched.
+func (s spaceState) Dims() int { return len(s.space.size) }
+
+func (s *spaceState) findShapes(ctx context.Context) error {
+	for i, value := range s.space.cells {
+		if i%s.space.size[0] == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		if value == s.opts.Background || s.seen[i] {
+			continue
+		}
+		s.seen[i] = true
+		cells, err := flood(ctx, s, len(s.space.size), s.space.coord(i), value)
+		if err != nil {
+			return err
+		}
+		s.components = append(s.components, cluster{cells: split(cells, len(s.space.size)), value: value})
+	}
+	return nil
+}
+
+// joined appends the cells one step from c for flood, leaving out those past a face that does not wrap.
+func (s *spaceState) joined(ns, c []int) []int {
+	for _, step := range s.steps {
+		n := len(ns)
+		for a := range c {
+			ns = append(ns, c[a]+step[a])
+		}
+		if !s.inside(ns[n:]) {
+			ns = ns[:n]
+		}
+	}
+	return ns
+}
+
+// take claims a cell for flood.
+func (s *spaceState) take(c []int, value int) bool {
+	i := s.space.index(wrapCoord(c, s.space.size))
+	if s.space.cells[i] != value || s.seen[i] {
+		return false
+	}
+	s.seen[i] = true
+	return true
+}
+
+// inside reports whether c lies within the space along every axis that does not wrap.
+func (s spaceState) inside(c []int) bool {
+	for a, plane := range s.opts.Plane {
+		if plane && (c[a] < 0 || c[a] >= s.space.size[a]) {
+			return false
+		}
+	}
+	return true
+}
+
+// dedupe keeps the first component of each equivalence class as a unique shape.
+func (s *spaceState) dedupe() {
+	for _, c := range s.components {
+		k := s.key(c)
+		i, ok := s.index[k]
+		if !ok {
+			i = len(s.shapes)
+			s.index[k] = i
+			s.shapes = append(s.shapes, c)
+			s.counts = append(s.counts, 0)
+		}
+		s.counts[i]++
+	}
+}
+
+// key is the canonical key of a cluster. One that reaches all the way around the space is wound, so that a copy of
+// it anywhere in the space has the same key.
+func (s spaceState) key(c cluster) string {
+	k := canonicalCoords(c.cells, s.turns, s.space.size, s.around(c))
+	if s.opts.AcrossValues {
+		return k
+	}
+	return strconv.Itoa(c.value) + ":" + k
+}
+
+// layout returns the cells of a cluster as they were found, or wound back into the space along the axes it reaches
+// all the way around, cut in the same place wherever it lies.
+func (s spaceState) layout(c cluster) [][]int {
+	around := s.around(c)
+	if around == nil {
+		return c.cells
+	}
+	step := make([]int, len(around))
+	for a := range step {
+		step[a] = 1
+	}
+	return wind(c.cells, s.space.size, step, around)
+}
+
+// around reports for each axis whether a cluster joins up with itself all the way around the space along it, or
+// returns nil when it does along none. Its cells are unrolled as they were found, as on a torus grid, so two
+// neighbors in the space that were not found beside each other are a turn apart.
+func (s spaceState) around(c cluster) []bool {
+	lower, upper := append([]int(nil), c.cells[0]...), append([]int(nil), c.cells[0]...)
+	for _, cell := range c.cells {
+		for a, x := range cell {
+			if x < lower[a] {
+				lower[a] = x
+			}
+			if x > upper[a] {
+				upper[a] = x
+			}
+		}
+	}
+	// Neighbors are at most a step apart, so a cluster narrower than the space along every axis cannot reach
+	// around it.
+	narrow := true
+	for a := range lower {
+		narrow = narrow && upper[a]-lower[a]+1 < s.space.size[a]
+	}
+	if narrow {
+		return nil
+	}
+	found := make(map[int][]int, len(c.cells))
+	for _, cell := range c.cells {
+		found[s.space.index(wrapCoord(cell, s.space.size))] = cell
+	}
+	around, wraps := make([]bool, len(s.space.size)), false
+	for _, cell := range c.cells {
+		for _, step := range s.steps {
+			n := nextCoord(cell, step)
+			if !s.inside(n) {
+				continue
+			}
+			f, ok := found[s.space.index(wrapCoord(n, s.space.size))]
+			if !ok || compareInts(f, cell) == 0 {
+				continue
+			}
+			for a := range n {
+				if f[a] != n[a] {
+					around[a], wraps = true, true
+				}
+			}
+		}
+	}
+	if !wraps {
+		return nil
+	}
+	return around
+}
+
+// nextCoord returns the cell one step from c.
+func nextCoord(c, step []int) []int {
+	n := make([]int, len(c))
+	for a := range c {
+		n[a] = c[a] + step[a]
+	}
+	return n
+}
+
+// wrapCoord returns the cell of the space that an unrolled cell lands on.
+func wrapCoord(c, size []int) []int {
+	w := make([]int, len(c))
+	for a := range c {
+		w[a] = wrap(c[a], size[a])
+	}
+	return w
+}
+
+// axisSteps lists the steps from a cell to its neighbors in n dimensions.
+func axisSteps(n int, adj Adjacency) [][]int {
+	var steps [][]int
+	step := make([]int, n)
+	for a := range step {
+		step[a] = -1
+	}
+	for {
+		moved := 0
+		for _, d := range step {
+			moved += abs(d)
+		}
+		if moved == 1 || (moved > 1 && adj == CornerAdjacent) {
+			steps = append(steps, append([]int(nil), step...))
+		}
+		// Count through every step with each axis in -1, 0 or 1.
+		a := 0
+		for ; a < n && step[a] == 1; a++ {
+			step[a] = -1
+		}
+		if a == n {
+			return steps
+		}
+		step[a]++
+	}
+}
+
+// axisTurns lists the moves of the equivalence as signed permutations of n axes, identity first. Each entry
+// holds, for each axis, the axis its coordinate is taken from plus one, negated when it is flipped. Turns are the
+// permutations whose swaps and flips add up to an even number, the rest are mirror images.
+func axisTurns(n int, e Equivalence) [][]int {
+	if e == Translation {
+		identity := make([]int, n)
+		for a := range identity {
+			identity[a] = a + 1
+		}
+		return [][]int{identity}
+	}
+	var turns, mirrors [][]int
+	for _, perm := range permutations(n) {
+		for signs := 0; signs < 1<<uint(n); signs++ {
+			t := make([]int, n)
+			odd := inversions(perm)
+			for a := range perm {
+				t[a] = perm[a] + 1
+				if signs&(1<<uint(a)) != 0 {
+					t[a] = -t[a]
+					odd++
+				}
+			}
+			if odd%2 == 0 {
+				turns = append(turns, t)
+			} else {
+				mirrors = append(mirrors, t)
+			}
+		}
+	}
+	if e == Rotation {
+		return turns
+	}
+	return append(turns, mirrors...)
+}
+
+// permutations returns every ordering of 0 to n-1, the identity first.
+func permutations(n int) [][]int {
+	if n == 0 {
+		return [][]int{{}}
+	}
+	var all [][]int
+	for _, p := range permutations(n - 1) {
+		for i := len(p); i >= 0; i-- {
+			q := make([]int, 0, n)
+			q = append(append(append(q, p[:i]...), n-1), p[i:]...)
+			all = append(all, q)
+		}
+	}
+	return all
+}
+
+// inversions counts the pairs of a permutation that are out of order.
+func inversions(p []int) int {
+	n := 0
+	for i := range p {
+		for j := i + 1; j < len(p); j++ {
+			if p[i] > p[j] {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// canonicalCoords is the smallest key among the layouts of cells reachable by the turns. Cells that reach all the
+// way around a torus of the size along the axes in around are wound after each turn, so where they were found
+// makes no difference. Around is nil for cells that do not.
+func canonicalCoords(cs [][]int, turns [][]int, size []int, around []bool) string {
+	if len(cs) == 0 {
+		return ""
+	}
+	var best string
+	dims := len(cs[0])
+	turned := split(make([]int, len(cs)*dims), dims)
+	turnedSize, turnedAround, step := make([]int, dims), make([]bool, dims), make([]int, dims)
+	for _, t := range turns {
+		for i, c := range cs {
+			for a, axis := range t {
+				if axis > 0 {
+					turned[i][a] = c[axis-1]
+				} else {
+					turned[i][a] = -c[-axis-1]
+				}
+			}
+		}
+		var k string
+		if around == nil {
+			k = layoutKey(normalizeInPlace(turned))
+		} else {
+			for a, axis := range t {
+				turnedSize[a], turnedAround[a], step[a] = size[abs(axis)-1], around[abs(axis)-1], 1
+			}
+			k = coordsKey(wind(turned, turnedSize, step, turnedAround))
+		}
+		if best == "" || k < best {
+			best = k
+		}
+	}
+	return best
+}
+
+// wind puts cells that reach all the way around a torus of the size along the axes in around back on it along
+// those axes, and moves them along each by whole steps to the turn that does not depend on where they were found:
+// the one where their cells, read step by step along the axis, come first. It returns the cells moved and leaves
+// cs as it is.
+func wind(cs [][]int, size, step []int, around []bool) [][]int {
+	var axes []int
+	for a, ok := range around {
+		if ok {
+			axes = append(axes, a)
+		}
+	}
+	wound := split(make([]int, len(cs)*len(size)), len(size))
+	for i, c := range cs {
+		for a := range c {
+			wound[i][a] = c[a]
+			if around[a] {
+				wound[i][a] = wrap(c[a], size[a])
+			}
+		}
+	}
+	if len(axes) == 0 {
+		return wound
+	}
+	var best [][]int
+	var bestKey string
+	var try func(cs [][]int, i int)
+	try = func(cs [][]int, i int) {
+		a := axes[i]
+		seq := blocks(cs, a, size[a], step[a])
+		if i == len(axes)-1 {
+			moved := turnAxis(cs, a, size[a], step[a]*leastRotation(seq))
+			if k := coordsKey(moved); best == nil || k < bestKey {
+				best, bestKey = moved, k
+			}
+			return
+		}
+		// What lies along the later axes still moves with them, so only how many cells each step holds is
+		// compared here, and every turn that reads least is tried.
+		counts := make([][]int, len(seq))
+		for j := range seq {
+			counts[j] = []int{len(seq[j]) / len(size)}
+		}
+		for _, k := range rotations(counts) {
+			try(turnAxis(cs, a, size[a], step[a]*k), i+1)
+		}
+	}
+	try(wound, 0)
+	return best
+}
+
+// turnAxis returns a copy of cells moved back by along axis a, wrapped to the size along it.
+func turnAxis(cs [][]int, a, size, by int) [][]int {
+	moved := split(make([]int, len(cs)*len(cs[0])), len(cs[0]))
+	for i, c := range cs {
+		copy(moved[i], c)
+		moved[i][a] = wrap(c[a]-by, size)
+	}
+	return moved
+}
+
+// blocks reads cells along an axis a step at a time, listing for each step the cells in it with their position
+// along the axis taken within the step, in a fixed order.
+func blocks(cs [][]int, a, size, step int) [][]int {
+	groups := make([][][]int, size/step)
+	for _, c := range cs {
+		t := append([]int(nil), c...)
+		t[a] %= step
+		groups[c[a]/step] = append(groups[c[a]/step], t)
+	}
+	seq := make([][]int, len(groups))
+	for j, g := range groups {
+		sort.Sort(lastAxisOrder(g))
+		for _, t := range g {
+			seq[j] = append(seq[j], t...)
+		}
+	}
+	return seq
+}
+
+// leastRotation returns the first start from which a cyclic sequence reads least.
+func leastRotation(seq [][]int) int {
+	n := len(seq)
+	i, j, k := 0, 1, 0
+	for i < n && j < n && k < n {
+		c := compareInts(seq[(i+k)%n], seq[(j+k)%n])
+		if c == 0 {
+			k++
+			continue
+		}
+		if c > 0 {
+			i += k + 1
+		} else {
+			j += k + 1
+		}
+		if i == j {
+			j++
+		}
+		k = 0
+	}
+	if i < j {
+		return i
+	}
+	return j
+}
+
+// rotations returns every start from which a cyclic sequence reads least, one for each time it repeats.
+func rotations(seq [][]int) []int {
+	n, p := len(seq), len(seq)
+	for q := 1; q < n; q++ {
+		if n%q != 0 {
+			continue
+		}
+		i := 0
+		for i < n && compareInts(seq[i], seq[(i+q)%n]) == 0 {
+			i++
+		}
+		if i == n {
+			p = q
+			break
+		}
+	}
+	var ks []int
+	for k := leastRotation(seq) % p; k < n; k += p {
+		ks = append(ks, k)
+	}
+	return ks
+}
+
+// compareInts compares two sequences element by element, a shorter one first when it starts the longer.
+func compareInts(a, b []int) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return len(a) - len(b)
+}
+
+// coordsKey is the layout of cells moved to the origin, independent of the order they were found in.
+func coordsKey(cs [][]int) string {
+	return layoutKey(normalizeCoords(cs))
+}
+
+// layoutKey writes out cells already normalized.
+func layoutKey(cs [][]int) string {
+	var b []byte
+	for _, c := range cs {
+		for _, x := range c {
+			b = strconv.AppendInt(b, int64(x), 10)
+			b = append(b, ',')
+		}
+		b = append(b, ';')
+	}
+	return string(b)
+}
+
+// normalizeCoords moves a copy of cells so the smallest coordinate along each axis is zero and sorts them with the
+// last axis varying slowest.
+func normalizeCoords(cs [][]int) [][]int {
+	if len(cs) == 0 {
+		return nil
+	}
+	normal := split(make([]int, len(cs)*len(cs[0])), len(cs[0]))
+	for i, c := range cs {
+		copy(normal[i], c)
+	}
+	return normalizeInPlace(normal)
+}
+
+// normalizeInPlace moves cells as normalizeCoords does, without copying them, and returns them.
+func normalizeInPlace(cs [][]int) [][]int {
+	o := append([]int(nil), cs[0]...)
+	for _, c := range cs {
+		for a := range c {
+			if c[a] < o[a] {
+				o[a] = c[a]
+			}
+		}
+	}
+	for _, c := range cs {
+		for a := range c {
+			c[a] -= o[a]
+		}
+	}
+	sort.Sort(lastAxisOrder(cs))
+	return cs
+}
+
+// lastAxisOrder sorts cells with the last axis varying slowest.
+type lastAxisOrder [][]int
+
+func (o lastAxisOrder) Len() int      { return len(o) }
+func (o lastAxisOrder) Swap(i, j int) { o[i], o[j] = o[j], o[i] }
+func (o lastAxisOrder) Less(i, j int) bool {
+	a, b := o[i], o[j]
+	for k := len(a) - 1; k >= 0; k-- {
+		if a[k] != b[k] {
+			return a[k] < b[k]
+		}
+	}
+	return false
+}
+
+// extent returns the number of cells a normalized layout spans along each axis.
+func extent(cs [][]int) []int {
+	if len(cs) == 0 {
+		return nil
+	}
+	e := make([]int, len(cs[0]))
+	for _, c := range cs {
+		for a := range c {
+			if c[a]+1 > e[a] {
+				e[a] = c[a] + 1
+			}
+		}
+	}
+	return e
+}
+
+// Print writes each unique shape as rows of columns, with its cells where layout puts them. Shapes with more than
+// two axes are written as a run of two dimensional slices, one blank line apart, with a further blank line for each
+// higher axis that starts over.
+func (s spaceState) Print(w io.Writer) {
+	g := s.opts.Glyphs.orDefault()
+	for _, shp := range s.shapes {
+		width := drawCoords(w, s.layout(shp), g)
+		fmt.Fprintln(w, strings.Repeat("-", width+len(leftPadding)))
+	}
+}
+
+// drawCoords draws cells of any number of axes as Print does, and returns the width drawn. Cells of two axes are
+// drawn as the cells of a square lattice.
+func drawCoords(w io.Writer, cells [][]int, g Glyphs) int {
+	cs := normalizeCoords(cells)
+	e := append(extent(cs), 1)
+	set := NewSpace(e...)
+	for _, c := range cs {
+		set.Set(append(c, 0), 1)
+	}
+	higher := NewSpace(append(e[2:], 1)...)
+	for h := range higher.cells {
+		if h > 0 {
+			fmt.Fprintln(w)
+			for hc := higher.coord(h); len(hc) > 1 && hc[0] == 0; hc = hc[1:] {
+				fmt.Fprintln(w)
+			}
+		}
+		for y := 0; y < e[1]; y++ {
+			var row strings.Builder
+			for x := 0; x < e[0]; x++ {
+				if set.cells[(h*e[1]+y)*e[0]+x] != 0 {
+					row.WriteRune(g.Set)
+				} else {
+					row.WriteRune(g.Unset)
+				}
+			}
+			fmt.Fprintf(w, "%s%s\n", leftPadding, row.String())
+		}
+	}
+	return e[0]
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
@@ -0,0 +1,277 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+// polyforms returns every fixed layout of n cells joined by faces in a space of dims axes, grown one cell at a
+// time.
+func polyforms(dims, n int) [][][]int {
+	start := make([]int, dims)
+	layouts := map[string][][]int{coordsKey([][]int{start}): {start}}
+	steps := axisSteps(dims, FaceAdjacent)
+	for size := 1; size < n; size++ {
+		grown := make(map[string][][]int)
+		for _, cs := range layouts {
+			have := make(map[string]bool)
+			for _, c := range cs {
+				have[fmt.Sprint(c)] = true
+			}
+			for _, c := range cs {
+				for _, step := range steps {
+					if n := nextCoord(c, step); !have[fmt.Sprint(n)] {
+						next := append(append([][]int(nil), cs...), n)
+						grown[coordsKey(next)] = next
+					}
+				}
+			}
+		}
+		layouts = grown
+	}
+	var all [][][]int
+	for _, cs := range layouts {
+		all = append(all, cs)
+	}
+	return all
+}
+
+func TestCanonicalCoords(t *testing.T) {
+	// Counts of fixed, one sided and free polyforms of one cell upwards, by number of axes. In four dimensions a
+	// turn can carry a shape onto its mirror image, so the one sided and free counts agree.
+	tt := []struct {
+		dims int
+		want map[Equivalence][]int
+	}{
+		{dims: 1, want: map[Equivalence][]int{Translation: {1, 1, 1}, Rotation: {1, 1, 1}, Reflection: {1, 1, 1}}},
+		{dims: 2, want: map[Equivalence][]int{
+			Translation: {1, 2, 6, 19, 63},
+			Rotation:    {1, 1, 2, 7, 18},
+			Reflection:  {1, 1, 2, 5, 12},
+		}},
+		{dims: 3, want: map[Equivalence][]int{
+			Translation: {1, 3, 15, 86, 534},
+			Rotation:    {1, 1, 2, 8, 29},
+			Reflection:  {1, 1, 2, 7, 23},
+		}},
+		{dims: 4, want: map[Equivalence][]int{
+			Translation: {1, 4, 28, 234},
+			Rotation:    {1, 1, 2, 7},
+			Reflection:  {1, 1, 2, 7},
+		}},
+	}
+	for _, tc := range tt {
+		for e, counts := range tc.want {
+			turns := axisTurns(tc.dims, e)
+			for n, count := range counts {
+				t.Run(strconv.Itoa(tc.dims)+"/"+e.String()+"/"+strconv.Itoa(n+1), func(t *testing.T) {
+					keys := make(map[string]bool)
+					for _, cs := range polyforms(tc.dims, n+1) {
+						keys[canonicalCoords(cs, turns, nil, nil)] = true
+					}
+					if len(keys) != count {
+						t.Fatalf("want %d shapes got %d", count, len(keys))
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestAxisTurns(t *testing.T) {
+	// n! permutations of the axes, each with 2^n choices of flips, half of them turns.
+	want := []int{1, 2, 8, 48, 384}
+	for n, count := range want {
+		t.Run(strconv.Itoa(n), func(t *testing.T) {
+			if got := len(axisTurns(n, Reflection)); got != count {
+				t.Fatalf("want %d moves got %d", count, got)
+			}
+			if got := len(axisTurns(n, Rotation)); n > 0 && got != count/2 {
+				t.Fatalf("want %d turns got %d", count/2, got)
+			}
+		})
+	}
+}
+
+func TestNewFromSpace(t *testing.T) {
+	strip := NewSpace(10)
+	for i, v := range []int{1, 1, 0, 2, 2, 0, 0, 1, 1, 1} {
+		strip.Set([]int{i}, v)
+	}
+	// two cells at opposite corners of a tesseract of side three, which touch across the corner when it wraps
+	tesseract := NewSpace(3, 3, 3, 3)
+	tesseract.Set([]int{0, 0, 0, 0}, 1)
+	tesseract.Set([]int{2, 2, 2, 2}, 1)
+	tt := []struct {
+		space      *Space
+		opts       SpaceOptions
+		wantShapes int
+		wantCount  int
+		err        error
+	}{
+		// runs of the same label in a strip, the last run wrapping around to join the first
+		{space: strip, wantShapes: 2, wantCount: 2},
+		{space: strip, opts: SpaceOptions{Plane: []bool{true}}, wantShapes: 3, wantCount: 3},
+		{space: strip, opts: SpaceOptions{Plane: []bool{true}, AcrossValues: true}, wantShapes: 2, wantCount: 3},
+		{space: tesseract, wantShapes: 1, wantCount: 2},
+		{space: tesseract, opts: SpaceOptions{Adjacency: CornerAdjacent}, wantShapes: 1, wantCount: 1},
+		{space: tesseract, opts: SpaceOptions{Adjacency: CornerAdjacent, Plane: []bool{false, false, false, true}}, wantShapes: 1, wantCount: 2},
+		{space: NewSpace(), err: errorNoAxes},
+		{space: NewSpace(2, 0), err: errorEmptyAxis},
+		{space: strip, opts: SpaceOptions{Plane: []bool{true, true}}, err: errorPlaneAxes},
+	}
+	for i, tc := range tt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			s, err := NewFromSpace(context.Background(), tc.space, tc.opts)
+			if err != tc.err {
+				t.Fatalf("want error %v got %v", tc.err, err)
+			}
+			if err != nil {
+				return
+			}
+			if len(s.shapes) != tc.wantShapes || len(s.components) != tc.wantCount {
+				t.Fatalf("want %d shapes and %d components got %d and %d", tc.wantShapes, tc.wantCount, len(s.shapes), len(s.components))
+			}
+		})
+	}
+}
+
+func TestSpaceMatchesGrid(t *testing.T) {
+	// A two dimensional space finds the same shapes as a square grid.
+	r := rand.New(rand.NewSource(7))
+	for i := 0; i < 50; i++ {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			rows, cols := 2+r.Intn(8), 2+r.Intn(8)
+			g := make([][]int, rows)
+			sp := NewSpace(cols, rows)
+			for y := range g {
+				g[y] = make([]int, cols)
+				for x := range g[y] {
+					g[y][x] = r.Intn(3)
+					sp.Set([]int{x, y}, g[y][x])
+				}
+			}
+			opts := Options{
+				Topology:     Topology(r.Intn(2)),
+				Connectivity: Connectivity(r.Intn(2)),
+				Equivalence:  Equivalence(r.Intn(3)),
+			}
+			spaceOpts := SpaceOptions{
+				Adjacency:   Adjacency(opts.Connectivity),
+				Plane:       []bool{opts.Topology == Plane, opts.Topology == Plane},
+				Equivalence: opts.Equivalence,
+			}
+			want, err := NewWithOptions(g, opts)
+			if err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			got, err := NewFromSpace(context.Background(), sp, spaceOpts)
+			if err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			if len(got.shapes) != len(want.shapes) || len(got.components) != want.total() {
+				t.Fatalf("want %d shapes and %d components got %d and %d", len(want.shapes), want.total(), len(got.shapes), len(got.components))
+			}
+		})
+	}
+
+	// Shapes reaching all the way around a torus match wherever they are cut, and are drawn as the grid draws them.
+	tt := []struct {
+		grid [][]int
+		opts Options
+	}{
+		{grid: [][]int{{1, 1}, {1, 1}}},
+		{grid: [][]int{
+			{1, 1, 0, 0},
+			{1, 1, 1, 1},
+			{0, 0, 0, 0},
+			{0, 0, 0, 0},
+			{0, 0, 0, 0},
+			{1, 0, 0, 1},
+			{1, 1, 1, 1},
+			{0, 0, 0, 0},
+		}},
+		{grid: [][]int{
+			{1, 0, 0, 1, 0, 0},
+			{1, 0, 0, 1, 0, 0},
+			{1, 1, 0, 0, 1, 1},
+			{1, 0, 0, 0, 1, 0},
+		}, opts: Options{Equivalence: Reflection}},
+		{grid: [][]int{
+			{1, 1, 0, 0},
+			{0, 1, 0, 0},
+			{0, 0, 1, 0},
+			{0, 0, 0, 1},
+		}, opts: Options{Connectivity: EightWay, Equivalence: Rotation}},
+	}
+	for i, tc := range tt {
+		t.Run("around"+strconv.Itoa(i), func(t *testing.T) {
+			sp := NewSpace(len(tc.grid[0]), len(tc.grid))
+			for y, row := range tc.grid {
+				for x, v := range row {
+					sp.Set([]int{x, y}, v)
+				}
+			}
+			want, err := NewWithOptions(tc.grid, tc.opts)
+			if err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			got, err := NewFromSpace(context.Background(), sp, SpaceOptions{
+				Adjacency:   Adjacency(tc.opts.Connectivity),
+				Equivalence: tc.opts.Equivalence,
+			})
+			if err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			if fmt.Sprint(got.counts) != fmt.Sprint(want.counts) {
+				t.Fatalf("want counts %v got %v", want.counts, got.counts)
+			}
+			var wb, gb bytes.Buffer
+			want.Print(&wb)
+			got.Print(&gb)
+			if wb.String() != gb.String() {
+				t.Fatalf("want\n%s\ngot\n%s", wb.String(), gb.String())
+			}
+		})
+	}
+}
+
+func TestPrintSpace(t *testing.T) {
+	tt := []struct {
+		size  []int
+		cells [][]int
+		want  string
+	}{
+		{size: []int{4}, cells: [][]int{{1}, {2}}, want: "    XX\n------\n"},
+		{
+			size:  []int{2, 2, 2, 2},
+			cells: [][]int{{0, 0, 0, 0}, {0, 0, 1, 0}, {1, 0, 1, 0}, {1, 0, 1, 1}},
+			want:  "    X \n\n    XX\n\n\n      \n\n     X\n------\n",
+		},
+	}
+	for i, tc := range tt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			sp := NewSpace(tc.size...)
+			for _, c := range tc.cells {
+				sp.Set(c, 1)
+			}
+			plane := make([]bool, len(tc.size))
+			for a := range plane {
+				plane[a] = true
+			}
+			s, err := NewFromSpace(context.Background(), sp, SpaceOptions{Plane: plane})
+			if err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			var b bytes.Buffer
+			s.Print(&b)
+			if b.String() != tc.want {
+				t.Fatalf("want\n%q\ngot\n%q", tc.want, b.String())
+			}
+		})
+	}
+}
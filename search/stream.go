@@ -0,0 +1,296 @@
+package search
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// streamComponent is a component whose cells have been read so far.
+type streamComponent struct {
+	value int
+	cells map[point]bool
+	// first is the component's first cell in row order, where a search of it begins.
+	first point
+}
+
+// found is a unique shape seen by a streaming search.
+type found struct {
+	shape shape
+	first point
+	count int
+}
+
+// streamer labels a grid one row at a time. It keeps the labels of the previous and current rows, the labels of
+// the first row when the grid wraps top to bottom, and the components that any of those rows belong to. Every
+// other component is complete, so it is searched, counted and dropped.
+type streamer struct {
+	*state
+	parent     map[int]int
+	open       map[int]*streamComponent
+	nextLabel  int
+	prev, curr []int
+	prevValues []int
+	currValues []int
+	top        []int
+	topValues  []int
+	found      map[string]*found
+	closed     int
+}
+
+// NewStream finds the unique shapes in a grid read from r one row per line, with cell values separated by spaces.
+// Blank lines are skipped. Only two rows of the grid are held at a time, together with the shapes that are still
+// growing; rows that touch the first row on a torus are held until the last row has been read, since the grid may
+// wrap from bottom to top.
+//
+// A streaming search keeps each unique shape and its count, but not every component, and it does not look for
+// holes, so a filter sees none. Progress reports have a Rows of zero, since the number of rows is not known until
+// the end.
+func NewStream(ctx context.Context, r io.Reader, opts Options) (*state, error) {
+	if len(opts.Preprocess) > 0 {
+		return nil, errorMorphGrid
+	}
+	st := &streamer{
+		state:  &state{index: make(map[string]int), opts: opts},
+		parent: make(map[int]int),
+		open:   make(map[int]*streamComponent),
+		found:  make(map[string]*found),
+	}
+	err := st.read(ctx, r)
+	if err != nil && ctx.Err() == nil {
+		return nil, err
+	}
+	if err == nil {
+		st.finish()
+	}
+	st.collect()
+	st.partial = err != nil
+	return st.state, err
+}
+
+func (st *streamer) read(ctx context.Context, r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, math.MaxInt32)
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if st.rows == 0 {
+			st.cols = len(fields)
+		}
+		if len(fields) != st.cols {
+			return errorRagged
+		}
+		values := make([]int, st.cols)
+		for i, f := range fields {
+			v, err := strconv.Atoi(f)
+			if err != nil {
+				return err
+			}
+			values[i] = v
+		}
+		st.addRow(values)
+		if st.opts.Progress != nil {
+			st.opts.Progress(Progress{RowsScanned: st.rows, Components: st.closed})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if st.rows == 0 {
+		return errorNoRows
+	}
+	return st.opts.checkSize(st.rows, st.cols)
+}
+
+// streamNeighbors returns the neighbors of a cell in row y that have already been labeled: those in the row above
+// and those to its left in the same row. On a torus the cell at the end of a row has the start of the row to its
+// right, which has been labeled too.
+func (st *streamer) streamNeighbors(p point) []point {
+	wide := state{rows: math.MaxInt32, cols: st.cols, opts: st.opts}
+	var ns []point
+	for _, n := range wide.neighbors(p) {
+		t := point{wrap(n.x, st.cols), n.y}
+		if (n.y == p.y-1 && p.y > 0) || (n.y == p.y && t.x < p.x) {
+			ns = append(ns, t)
+		}
+	}
+	return ns
+}
+
+func (st *streamer) addRow(values []int) {
+	y := st.rows
+	st.rows++
+	st.prev, st.prevValues = st.curr, st.currValues
+	st.curr, st.currValues = make([]int, st.cols), values
+
+	for x, v := range values {
+		st.curr[x] = -1
+		if v == st.opts.Background {
+			continue
+		}
+		label := st.nextLabel
+		st.nextLabel++
+		st.parent[label] = label
+		st.open[label] = &streamComponent{value: v, cells: map[point]bool{{x, y}: true}, first: point{x, y}}
+		st.curr[x] = label
+		for _, n := range st.streamNeighbors(point{x, y}) {
+			if n.y == y && values[n.x] == v {
+				st.union(label, st.curr[n.x])
+			} else if n.y == y-1 && st.prevValues[n.x] == v {
+				st.union(label, st.prev[n.x])
+			}
+		}
+	}
+
+	if y == 0 && st.opts.Topology == Torus {
+		st.top, st.topValues = st.curr, st.currValues
+	}
+	st.compact()
+}
+
+func (st *streamer) find(label int) int {
+	for st.parent[label] != label {
+		st.parent[label] = st.parent[st.parent[label]]
+		label = st.parent[label]
+	}
+	return label
+}
+
+// union joins the components of two labels, keeping the cells under the root of the larger one.
+func (st *streamer) union(a, b int) {
+	ra, rb := st.find(a), st.find(b)
+	if ra == rb {
+		return
+	}
+	ca, cb := st.open[ra], st.open[rb]
+	if len(ca.cells) < len(cb.cells) {
+		ra, rb, ca, cb = rb, ra, cb, ca
+	}
+	for p := range cb.cells {
+		ca.cells[p] = true
+	}
+	if cb.first.y < ca.first.y || (cb.first.y == ca.first.y && cb.first.x < ca.first.x) {
+		ca.first = cb.first
+	}
+	st.parent[rb] = ra
+	delete(st.open, rb)
+}
+
+// compact points the labels still in use straight at their roots, forgets every other label and completes the
+// components that can no longer grow.
+func (st *streamer) compact() {
+	keep := make(map[int]bool)
+	for _, labels := range [][]int{st.curr, st.top} {
+		for x, l := range labels {
+			if l >= 0 {
+				labels[x] = st.find(l)
+				keep[labels[x]] = true
+			}
+		}
+	}
+	parent := make(map[int]int, len(keep))
+	for l := range keep {
+		parent[l] = l
+	}
+	st.parent = parent
+	for l, c := range st.open {
+		if !keep[l] {
+			st.complete(c, math.MaxInt32)
+			delete(st.open, l)
+		}
+	}
+}
+
+// finish joins the last row to the first on a torus and completes the components left open.
+func (st *streamer) finish() {
+	if st.opts.Topology == Torus {
+		last := st.rows - 1
+		for x, l := range st.curr {
+			if l < 0 {
+				continue
+			}
+			p := point{x, last}
+			for _, n := range st.neighbors(p) {
+				if n.y != last+1 {
+					continue
+				}
+				t := n.transform(st.rows, st.cols)
+				if st.top[t.x] >= 0 && st.topValues[t.x] == st.currValues[x] {
+					st.union(l, st.top[t.x])
+				}
+			}
+		}
+	}
+	for _, c := range st.open {
+		st.complete(c, st.rows)
+	}
+	st.open = nil
+}
+
+// complete searches a component from its first cell, as a serial search would, and counts its shape. Rows is the
+// height used to wrap from top to bottom; components completed before the end never cross that edge.
+func (st *streamer) complete(c *streamComponent, rows int) {
+	l := completion{
+		state: &state{rows: rows, cols: st.cols, opts: st.opts},
+		cells: c.cells,
+		seen:  map[point]bool{c.first: true},
+	}
+	cells, _ := flood(context.Background(), l, 2, []int{c.first.x, c.first.y}, c.value)
+	shp := shape{value: c.value, points: points(cells)}
+
+	st.closed++
+	if !st.keep(shp) {
+		return
+	}
+	k := st.key(shp)
+	f, ok := st.found[k]
+	if !ok {
+		f = &found{shape: shp, first: c.first}
+		st.found[k] = f
+	} else if c.first.y < f.first.y || (c.first.y == f.first.y && c.first.x < f.first.x) {
+		f.shape, f.first = shp, c.first
+	}
+	f.count++
+}
+
+// completion searches the cells of a component read so far, for flood. Its cells are joined as those of a grid of
+// the given rows.
+type completion struct {
+	*state
+	cells, seen map[point]bool
+}
+
+func (l completion) take(c []int, value int) bool {
+	t := point{c[0], c[1]}.transform(l.rows, l.cols)
+	if !l.cells[t] || l.seen[t] {
+		return false
+	}
+	l.seen[t] = true
+	return true
+}
+
+// collect orders the unique shapes by their first occurrence, as a serial search finds them.
+func (st *streamer) collect() {
+	var all []*found
+	for _, f := range st.found {
+		all = append(all, f)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		a, b := all[i].first, all[j].first
+		return a.y < b.y || (a.y == b.y && a.x < b.x)
+	})
+	for _, f := range all {
+		st.index[st.key(f.shape)] = len(st.shapes)
+		st.shapes = append(st.shapes, f.shape)
+		st.counts = append(st.counts, f.count)
+	}
+}
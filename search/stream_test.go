@@ -0,0 +1,116 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func gridText(grid [][]int) string {
+	var b strings.Builder
+	for _, row := range grid {
+		for i, v := range row {
+			if i > 0 {
+				b.WriteString(" ")
+			}
+			fmt.Fprint(&b, v)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func TestNewStream(t *testing.T) {
+	r := rand.New(rand.NewSource(4))
+	opts := []Options{
+		{},
+		{Topology: Plane},
+		{Connectivity: EightWay},
+		{Topology: Plane, Connectivity: EightWay},
+		{Equivalence: Rotation, AcrossValues: true},
+		{Topology: Plane, Lattice: Hex},
+		{Topology: Plane, Lattice: Triangle},
+	}
+	for i := 0; i < 60; i++ {
+		grid := randomGrid(r, 1+r.Intn(25), 1+r.Intn(25), 1+r.Intn(3), 0.2+r.Float64()*0.5)
+		for j, o := range opts {
+			t.Run(strconv.Itoa(i)+"/"+strconv.Itoa(j), func(t *testing.T) {
+				want, err := NewWithOptions(grid, o)
+				if err != nil {
+					t.Fatal("unexpected error", err)
+				}
+				got, err := NewStream(context.Background(), strings.NewReader(gridText(grid)), o)
+				if err != nil {
+					t.Fatal("unexpected error", err)
+				}
+				if got.rows != want.rows || got.cols != want.cols {
+					t.Fatalf("want %dx%d got %dx%d", want.rows, want.cols, got.rows, got.cols)
+				}
+				if len(got.shapes) != len(want.shapes) {
+					t.Fatalf("want %d shapes got %d", len(want.shapes), len(got.shapes))
+				}
+				for k := range want.shapes {
+					ws, gs := want.shapes[k], got.shapes[k]
+					if len(ws.points) != len(gs.points) || want.counts[k] != got.counts[k] {
+						t.Fatalf("shape %d differs", k)
+					}
+					for m := range ws.points {
+						if !ws.points[m].match(gs.points[m]) {
+							t.Fatalf("shape %d differs at point %d", k, m)
+						}
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestNewStreamErrors(t *testing.T) {
+	tt := []struct {
+		input string
+		err   error
+	}{
+		{"", errorNoRows},
+		{"1 0\n1\n", errorRagged},
+	}
+	for i, tc := range tt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			_, err := NewStream(context.Background(), strings.NewReader(tc.input), Options{})
+			if err != tc.err {
+				t.Fatalf("want %v got %v", tc.err, err)
+			}
+		})
+	}
+	if _, err := NewStream(context.Background(), strings.NewReader("1 x\n"), Options{}); err == nil {
+		t.Fatal("expected error")
+	}
+	if _, err := NewStream(context.Background(), strings.NewReader("1 0\n"), Options{Lattice: Hex}); err != errorHexRows {
+		t.Fatalf("want %v got %v", errorHexRows, err)
+	}
+}
+
+func TestNewStreamCancel(t *testing.T) {
+	input := "1 0 0\n0 0 0\n1 1 0\n0 0 0\n1 1 1\n0 0 0\n"
+	ctx, cancel := context.WithCancel(context.Background())
+	s, err := NewStream(ctx, strings.NewReader(input), Options{Progress: func(p Progress) {
+		if p.RowsScanned == 4 {
+			cancel()
+		}
+	}})
+	if err != context.Canceled {
+		t.Fatalf("want %v got %v", context.Canceled, err)
+	}
+	// the single cell in the first row is held back in case the grid wraps onto it from the bottom
+	var w bytes.Buffer
+	s.Print(&w)
+	want := "    XX\n------\n"
+	if w.String() != want {
+		t.Logf("want %q", want)
+		t.Logf("got  %q", w.String())
+		t.Fatal()
+	}
+}
@@ -0,0 +1,94 @@
+package search
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+const (
+	// cellSize is the side of a square or triangular cell and the distance from the center of a hex cell to its
+	// corners.
+	cellSize = 20.0
+	// svgGap is the space left around and between shapes.
+	svgGap = 10.0
+)
+
+// WriteSVG draws each unique shape as an SVG image, one under another. Hex cells are drawn with a corner at the top
+// and odd rows pushed half a cell right, and neighboring triangles overlap by half their width. Each shape's group
+// has the class "value-N" for its label N, so labels can be styled apart.
+func (s state) WriteSVG(w io.Writer) error {
+	var body strings.Builder
+	width, y := 0.0, svgGap
+	for _, shp := range s.shapes {
+		cells, wide, high := s.outlines(shp)
+		fmt.Fprintf(&body, "  <g class=\"value-%d\" transform=\"translate(%s %s)\">\n", shp.value, svgFloat(svgGap), svgFloat(y))
+		for _, c := range cells {
+			var coords []string
+			for _, v := range c {
+				coords = append(coords, svgFloat(v[0])+","+svgFloat(v[1]))
+			}
+			fmt.Fprintf(&body, "    <polygon points=\"%s\"/>\n", strings.Join(coords, " "))
+		}
+		body.WriteString("  </g>\n")
+		if wide > width {
+			width = wide
+		}
+		y += high + svgGap
+	}
+	_, err := fmt.Fprintf(w, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%s\" height=\"%s\">\n%s</svg>\n",
+		svgFloat(width+2*svgGap), svgFloat(y), body.String())
+	return err
+}
+
+// outlines returns the corners of each cell of a shape as layout puts them, in row order with the shape's upper left
+// at the origin, and the width and height the cells take up.
+func (s state) outlines(shp shape) (cells [][][2]float64, width, height float64) {
+	ps := s.layout(shp)
+	if s.opts.Lattice == Hex {
+		half := cellSize * math.Sqrt(3) / 2
+		for _, p := range doubled(ps) {
+			cx, cy := half*float64(p.x+1), cellSize*(1.5*float64(p.y)+1)
+			var c [][2]float64
+			for k := 0; k < 6; k++ {
+				a := math.Pi/3*float64(k) - math.Pi/6
+				c = append(c, [2]float64{cx + cellSize*math.Cos(a), cy + cellSize*math.Sin(a)})
+			}
+			cells = append(cells, c)
+			width, height = math.Max(width, cx+half), math.Max(height, cy+cellSize)
+		}
+		return cells, width, height
+	}
+	if s.opts.Lattice == Triangle {
+		o, high := origin(ps), cellSize*math.Sqrt(3)/2
+		ps = append([]point(nil), ps...)
+		sort.Slice(ps, func(i, j int) bool { return before(ps[i], ps[j]) })
+		for _, p := range ps {
+			left, top := cellSize/2*float64(p.x-o.x), high*float64(p.y-o.y)
+			base, tip := top+high, top
+			if !pointsUp(p) {
+				base, tip = top, top+high
+			}
+			cells = append(cells, [][2]float64{{left, base}, {left + cellSize, base}, {left + cellSize/2, tip}})
+			width, height = math.Max(width, left+cellSize), math.Max(height, top+high)
+		}
+		return cells, width, height
+	}
+	for _, p := range normalize(ps) {
+		x, y := cellSize*float64(p.x), cellSize*float64(p.y)
+		cells = append(cells, [][2]float64{{x, y}, {x + cellSize, y}, {x + cellSize, y + cellSize}, {x, y + cellSize}})
+		width, height = math.Max(width, x+cellSize), math.Max(height, y+cellSize)
+	}
+	return cells, width, height
+}
+
+// svgFloat writes f to two decimal places without trailing zeros.
+func svgFloat(f float64) string {
+	v := strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.2f", f), "0"), ".")
+	if v == "-0" {
+		return "0"
+	}
+	return v
+}
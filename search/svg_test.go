@@ -0,0 +1,70 @@
+package search
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteSVG(t *testing.T) {
+	s, err := New([][]int{
+		{1, 1, 0},
+		{0, 0, 0},
+	})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	var b bytes.Buffer
+	if err := s.WriteSVG(&b); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	want := `<svg xmlns="http://www.w3.org/2000/svg" width="60" height="40">
+  <g class="value-1" transform="translate(10 10)">
+    <polygon points="0,0 20,0 20,20 0,20"/>
+    <polygon points="20,0 40,0 40,20 20,20"/>
+  </g>
+</svg>
+`
+	if b.String() != want {
+		t.Fatalf("want\n%s\ngot\n%s", want, b.String())
+	}
+
+	h, err := NewWithOptions([][]int{
+		{1, 0},
+		{0, 0},
+	}, Options{Lattice: Hex})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	b.Reset()
+	if err := h.WriteSVG(&b); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if !bytes.Contains(b.Bytes(), []byte(`<polygon points="34.64,10 34.64,30 17.32,40 0,30 0,10 17.32,0"/>`)) {
+		t.Fatalf("hex cell not drawn as a hexagon\n%s", b.String())
+	}
+
+	// a shape wrapping all the way around is drawn as a block, as it is printed
+	s, err = New([][]int{
+		{1, 1},
+		{1, 1},
+	})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	b.Reset()
+	if err := s.WriteSVG(&b); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	want = `<svg xmlns="http://www.w3.org/2000/svg" width="60" height="60">
+  <g class="value-1" transform="translate(10 10)">
+    <polygon points="0,0 20,0 20,20 0,20"/>
+    <polygon points="20,0 40,0 40,20 20,20"/>
+    <polygon points="0,20 20,20 20,40 0,40"/>
+    <polygon points="20,20 40,20 40,40 20,40"/>
+  </g>
+</svg>
+`
+	if b.String() != want {
+		t.Fatalf("want\n%s\ngot\n%s", want, b.String())
+	}
+}
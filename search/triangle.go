@@ -0,0 +1,96 @@
+package search
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+const errorTriangleSize = stateError("a triangular grid on a torus needs an even number of rows and columns")
+
+// pointsUp reports whether the triangle in column p.x of row p.y points up, with its base along the bottom.
+func pointsUp(p point) bool {
+	return (p.x+p.y)&1 == 0
+}
+
+// triangleNeighbors returns the three cells sharing an edge with p: those to either side and the one across its
+// base.
+func triangleNeighbors(p point) []point {
+	across := point{p.x, p.y - 1}
+	if pointsUp(p) {
+		across.y = p.y + 1
+	}
+	return []point{across, {p.x + 1, p.y}, {p.x - 1, p.y}}
+}
+
+// triangleCorners returns the twelve cells sharing a corner with p. Five lie in the row across its base, three in
+// the row past its tip and four in its own row.
+func triangleCorners(p point) []point {
+	base, tip := p.y-1, p.y+1
+	if pointsUp(p) {
+		base, tip = p.y+1, p.y-1
+	}
+	ns := []point{{p.x - 2, p.y}, {p.x - 1, p.y}, {p.x + 1, p.y}, {p.x + 2, p.y}}
+	for x := p.x - 2; x <= p.x+2; x++ {
+		ns = append(ns, point{x, base})
+	}
+	for x := p.x - 1; x <= p.x+1; x++ {
+		ns = append(ns, point{x, tip})
+	}
+	return ns
+}
+
+// centers places each triangle at three times its center in a basis of two lattice edges a sixth of a turn apart,
+// so a sixth of a turn and a flip move centers just as they move hex cells in axial coordinates. Triangles pointing
+// up are centered a third of the way along both edges of their corner and the others two thirds.
+func centers(ps []point) []point {
+	cs := make([]point, len(ps))
+	for i, p := range ps {
+		j := -p.y
+		if pointsUp(p) {
+			k := (p.x + p.y) / 2
+			cs[i] = point{3*k + 1, 3*j + 1}
+		} else {
+			k := (p.x + p.y - 1) / 2
+			cs[i] = point{3*k + 2, 3*j + 2}
+		}
+	}
+	return cs
+}
+
+// centersKey is the key of a layout of triangle centers. Centers only slide by whole cells, so the key also holds
+// where the smallest coordinates fall between lattice corners, telling a triangle pointing up from one pointing
+// down.
+func centersKey(cs []point) string {
+	o := origin(cs)
+	return fmt.Sprintf("%d,%d:%s", (o.x%3+3)%3, (o.y%3+3)%3, (shape{points: cs}).key())
+}
+
+// drawTriangles draws triangles pointing up as ^ and those pointing down as v, and returns the width drawn.
+func drawTriangles(w io.Writer, ps []point, g Glyphs) int {
+	o := origin(ps)
+	var width, height int
+	for _, p := range ps {
+		if p.x-o.x+1 > width {
+			width = p.x - o.x + 1
+		}
+		if p.y-o.y+1 > height {
+			height = p.y - o.y + 1
+		}
+	}
+	lines := make([][]rune, height)
+	for i := range lines {
+		lines[i] = []rune(strings.Repeat(string(g.Unset), width))
+	}
+	for _, p := range ps {
+		c := 'v'
+		if pointsUp(p) {
+			c = '^'
+		}
+		lines[p.y-o.y][p.x-o.x] = c
+	}
+	for _, l := range lines {
+		fmt.Fprintf(w, "%s%s\n", leftPadding, string(l))
+	}
+	return width
+}
@@ -0,0 +1,164 @@
+package search
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+)
+
+func TestTriangleNeighbors(t *testing.T) {
+	for y := -3; y <= 3; y++ {
+		for x := -3; x <= 3; x++ {
+			p := point{x, y}
+			for _, neighbors := range []func(point) []point{triangleNeighbors, triangleCorners} {
+				for _, n := range neighbors(p) {
+					if n.match(p) || !contains(neighbors(n), p) {
+						t.Fatalf("%v is a neighbor of %v but not the other way round", n, p)
+					}
+				}
+			}
+			for _, n := range triangleNeighbors(p) {
+				if pointsUp(n) == pointsUp(p) {
+					t.Fatalf("%v and %v point the same way", p, n)
+				}
+			}
+		}
+	}
+}
+
+// polyiamonds returns every fixed polyiamond of n cells, as layouts grown one cell at a time from a triangle
+// pointing up and one pointing down.
+func polyiamonds(n int) [][]point {
+	layouts := map[string][]point{}
+	for _, p := range []point{{0, 0}, {1, 0}} {
+		layouts[centersKey(centers([]point{p}))] = []point{p}
+	}
+	for size := 1; size < n; size++ {
+		grown := make(map[string][]point)
+		for _, ps := range layouts {
+			for _, p := range ps {
+				for _, c := range triangleNeighbors(p) {
+					if !contains(ps, c) {
+						next := append(append([]point(nil), ps...), c)
+						grown[centersKey(centers(next))] = next
+					}
+				}
+			}
+		}
+		layouts = grown
+	}
+	var all [][]point
+	for _, ps := range layouts {
+		all = append(all, ps)
+	}
+	return all
+}
+
+func TestTriangleCanonical(t *testing.T) {
+	// Counts of fixed, one sided and free polyiamonds of one to six cells.
+	want := map[Equivalence][]int{
+		Translation: {2, 3, 6, 14, 36, 94},
+		Rotation:    {1, 1, 1, 4, 6, 19},
+		Reflection:  {1, 1, 1, 3, 4, 12},
+	}
+	for e, counts := range want {
+		for n, count := range counts {
+			t.Run(e.String()+"/"+strconv.Itoa(n+1), func(t *testing.T) {
+				keys := make(map[string]bool)
+				for _, ps := range polyiamonds(n + 1) {
+					keys[(shape{points: ps}).canonical(e, Triangle)] = true
+				}
+				if len(keys) != count {
+					t.Fatalf("want %d shapes got %d", count, len(keys))
+				}
+			})
+		}
+	}
+}
+
+func TestTriangleSearch(t *testing.T) {
+	tt := []struct {
+		grid       [][]int
+		opts       Options
+		wantShapes int
+		wantCount  int
+		wantHoles  int
+		err        error
+	}{
+		{
+			// an up and a down triangle side by side make a diamond, as do an up triangle above a down one, but a
+			// down triangle above an up one only touch at their tips
+			grid: [][]int{
+				{1, 1, 0, 0, 0, 0},
+				{0, 0, 0, 0, 0, 0},
+				{0, 0, 0, 0, 0, 1},
+				{0, 0, 0, 0, 0, 1},
+				{0, 0, 0, 0, 0, 0},
+				{0, 1, 0, 0, 0, 0},
+				{0, 1, 0, 0, 0, 0},
+				{0, 0, 0, 0, 0, 0},
+			},
+			opts:       Options{Lattice: Triangle, Equivalence: Rotation},
+			wantShapes: 2,
+			wantCount:  4,
+		},
+		{
+			// the twelve triangles around a triangle enclose it
+			grid: [][]int{
+				{0, 0, 0, 0, 0, 0, 0, 0},
+				{0, 1, 1, 1, 1, 1, 0, 0},
+				{0, 1, 1, 0, 1, 1, 0, 0},
+				{0, 0, 1, 1, 1, 0, 0, 0},
+				{0, 0, 0, 0, 0, 0, 0, 0},
+				{0, 0, 0, 0, 0, 0, 0, 0},
+			},
+			opts:       Options{Lattice: Triangle},
+			wantShapes: 1,
+			wantCount:  1,
+			wantHoles:  1,
+		},
+		{
+			grid: [][]int{
+				{1, 0, 0},
+				{0, 0, 0},
+			},
+			opts: Options{Lattice: Triangle},
+			err:  errorTriangleSize,
+		},
+	}
+	for i, tc := range tt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			s, err := NewWithOptions(tc.grid, tc.opts)
+			if err != tc.err {
+				t.Fatalf("want error %v got %v", tc.err, err)
+			}
+			if err != nil {
+				return
+			}
+			if len(s.shapes) != tc.wantShapes || s.total() != tc.wantCount {
+				t.Fatalf("want %d shapes and %d components got %d and %d", tc.wantShapes, tc.wantCount, len(s.shapes), s.total())
+			}
+			if holes := len(s.shapes[0].holes); holes != tc.wantHoles {
+				t.Fatalf("want %d holes got %d", tc.wantHoles, holes)
+			}
+		})
+	}
+}
+
+func TestPrintTriangles(t *testing.T) {
+	s, err := NewWithOptions([][]int{
+		{0, 0, 0, 0},
+		{1, 1, 1, 0},
+		{0, 1, 0, 0},
+		{0, 0, 0, 0},
+	}, Options{Lattice: Triangle})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	var b bytes.Buffer
+	s.Print(&b)
+	want := "    v^v\n     v \n-------\n"
+	if b.String() != want {
+		t.Fatalf("want\n%q\ngot\n%q", want, b.String())
+	}
+}
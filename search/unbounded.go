@@ -0,0 +1,75 @@
+package search
+
+import "context"
+
+const errorNoCells = stateError("no cells given")
+const errorTooWide = stateError("cells are too far apart to search")
+
+// NewUnbounded finds the unique shapes among cells set on an unbounded plane, given as the column and row of each
+// set cell. Coordinates may be negative or very large. The cells are searched as a sparse plane just large enough
+// to hold them, so space outside them never wraps. Contours and polygons are written in the given coordinates. On a
+// hex lattice the cells are given in axial coordinates.
+func NewUnbounded(ctx context.Context, cells [][2]int, opts Options) (*state, error) {
+	if len(cells) == 0 {
+		return nil, errorNoCells
+	}
+	if len(opts.Preprocess) > 0 {
+		return nil, errorMorphGrid
+	}
+	if opts.Filter.uses(Edge) {
+		return nil, errorFilterEdge
+	}
+	ps := make([]point, len(cells))
+	for i, c := range cells {
+		ps[i] = point{c[0], c[1]}
+		if opts.Lattice == Hex {
+			ps[i].x, ps[i].y = AxialToOffset(c[0], c[1])
+		}
+	}
+	lo := origin(ps)
+	hiX, hiY := lo.x, lo.y
+	// Hex rows keep their stagger only if the first row searched is even, and triangles keep pointing the same
+	// way only if the first cell searched points up.
+	switch {
+	case opts.Lattice == Hex:
+		lo.y -= lo.y & 1
+	case opts.Lattice == Triangle && !pointsUp(lo):
+		lo.x--
+	}
+	for _, p := range ps {
+		if p.x > hiX {
+			hiX = p.x
+		}
+		if p.y > hiY {
+			hiY = p.y
+		}
+	}
+	cols, rows := hiX-lo.x+1, hiY-lo.y+1
+	if cols <= 0 || rows <= 0 {
+		return nil, errorTooWide
+	}
+	g := NewSparse(rows, cols)
+	for _, p := range ps {
+		g.Set(p.x-lo.x, p.y-lo.y, set)
+	}
+	opts.Topology = Plane
+	st, err := newGridState(g, opts)
+	if err != nil {
+		return nil, err
+	}
+	st.origin = lo
+	err = st.findShapes(ctx)
+	st.partial = err != nil
+	return st, err
+}
+
+// unbounded moves grid coordinates to where they lie on an unbounded plane.
+func (s state) unbounded(p point) point {
+	return point{p.x + s.origin.x, p.y + s.origin.y}
+}
+
+// shift returns the transform a with the grid's origin applied first.
+func (s state) shift(a Affine) Affine {
+	a[0], a[3] = a.apply(float64(s.origin.x), float64(s.origin.y))
+	return a
+}
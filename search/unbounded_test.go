@@ -0,0 +1,68 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestNewUnbounded(t *testing.T) {
+	ring := [][2]int{{-1, -1}, {0, -1}, {1, -1}, {-1, 0}, {1, 0}, {-1, 1}, {0, 1}, {1, 1}}
+	tt := []struct {
+		cells  [][2]int
+		shapes int
+		total  int
+		holes  int
+		err    error
+	}{
+		{cells: ring, shapes: 1, total: 1, holes: 1},
+		{cells: append([][2]int{{1 << 40, -(1 << 40)}, {1<<40 + 5, 3}}, ring...), shapes: 2, total: 3, holes: 1},
+		// Cells at the edges of the area searched would touch if it wrapped.
+		{cells: [][2]int{{-4, 0}, {4, 0}}, shapes: 1, total: 2},
+		{cells: nil, err: errorNoCells},
+		{cells: [][2]int{{math.MinInt64, 0}, {math.MaxInt64, 0}}, err: errorTooWide},
+	}
+	for i, tc := range tt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			s, err := NewUnbounded(context.Background(), tc.cells, Options{})
+			if err != tc.err {
+				t.Fatalf("want error %v got %v", tc.err, err)
+			}
+			if err != nil {
+				return
+			}
+			if len(s.shapes) != tc.shapes || s.total() != tc.total {
+				t.Fatalf("want %d shapes and %d components got %d and %d", tc.shapes, tc.total, len(s.shapes), s.total())
+			}
+			var holes int
+			for _, c := range s.components {
+				holes += len(c.holes)
+			}
+			if holes != tc.holes {
+				t.Fatalf("want %d holes got %d", tc.holes, holes)
+			}
+		})
+	}
+}
+
+func TestUnboundedCoordinates(t *testing.T) {
+	s, err := NewUnbounded(context.Background(), [][2]int{{-3, 7}, {-2, 7}, {100, 100}}, Options{})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	var wkt bytes.Buffer
+	if err := s.WriteWKT(&wkt, Identity); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	want := "POLYGON ((-3 7, -1 7, -1 8, -3 8, -3 7))\nPOLYGON ((100 100, 101 100, 101 101, 100 101, 100 100))\n"
+	if wkt.String() != want {
+		t.Fatalf("want\n%s\ngot\n%s", want, wkt.String())
+	}
+	var contours bytes.Buffer
+	s.PrintContours(&contours)
+	if !bytes.Contains(contours.Bytes(), []byte("outer: (-3,7)")) {
+		t.Fatalf("contours not in plane coordinates\n%s", contours.String())
+	}
+}
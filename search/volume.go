@@ -0,0 +1,85 @@
+package search
+
+import "context"
+
+// Volume is a three dimensional grid of labeled voxels, indexed by slice, row and column.
+type Volume [][][]int
+
+// VoxelConnectivity is the way voxels join into shapes.
+type VoxelConnectivity int
+
+const (
+	// SixWay joins voxels that share a face.
+	SixWay VoxelConnectivity = iota
+	// TwentySixWay joins voxels that share a face, an edge or a corner.
+	TwentySixWay
+)
+
+var voxelConnectivities = []string{"6", "26"}
+
+func (c VoxelConnectivity) String() string { return voxelConnectivities[c] }
+
+// UnmarshalText accepts 6 or 26.
+func (c *VoxelConnectivity) UnmarshalText(b []byte) error {
+	i, err := parseOption(string(b), voxelConnectivities)
+	*c = VoxelConnectivity(i)
+	return err
+}
+
+// VolumeOptions controls how a volume is searched.
+type VolumeOptions struct {
+	// Background is the voxel value treated as empty space.
+	Background int
+	// AcrossValues treats shapes with the same layout as duplicates even when their labels differ.
+	AcrossValues bool
+	// Connectivity decides whether voxels touching only at an edge or a corner belong to the same shape.
+	Connectivity VoxelConnectivity
+	// Plane ends the volume at its faces along each axis, in the order column, row, slice. Axes that are not
+	// ended wrap around to the opposite face.
+	Plane [3]bool
+	// Equivalence decides which shapes count as duplicates. Rotation allows the 24 turns of a cube and Reflection
+	// the 48 turns and mirror images.
+	Equivalence Equivalence
+	// Glyphs are the characters Print draws voxels with.
+	Glyphs Glyphs
+}
+
+// NewVolume finds the unique shapes in a volume, stopping early if ctx is done. A volume is searched as a space of
+// three axes.
+func NewVolume(ctx context.Context, v Volume, opts VolumeOptions) (*spaceState, error) {
+	if len(v) == 0 {
+		return nil, errorNoRows
+	}
+	if len(v[0]) == 0 {
+		return nil, errorNoRows
+	}
+	if len(v[0][0]) == 0 {
+		return nil, errorNoCols
+	}
+	sp := NewSpace(len(v[0][0]), len(v[0]), len(v))
+	for z, slice := range v {
+		if len(slice) != sp.size[1] {
+			return nil, errorRagged
+		}
+		for y, row := range slice {
+			if len(row) != sp.size[0] {
+				return nil, errorRagged
+			}
+			for x, value := range row {
+				sp.cells[sp.index([]int{x, y, z})] = value
+			}
+		}
+	}
+	adj := FaceAdjacent
+	if opts.Connectivity == TwentySixWay {
+		adj = CornerAdjacent
+	}
+	return NewFromSpace(ctx, sp, SpaceOptions{
+		Background:   opts.Background,
+		AcrossValues: opts.AcrossValues,
+		Adjacency:    adj,
+		Plane:        opts.Plane[:],
+		Equivalence:  opts.Equivalence,
+		Glyphs:       opts.Glyphs,
+	})
+}
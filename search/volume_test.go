@@ -0,0 +1,122 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestNewVolume(t *testing.T) {
+	// two voxels touching at a corner in adjacent slices, and a voxel on the last slice under the first
+	v := Volume{
+		{
+			{1, 0, 0},
+			{0, 0, 0},
+			{0, 0, 0},
+		},
+		{
+			{0, 0, 0},
+			{0, 1, 0},
+			{0, 0, 0},
+		},
+		{
+			{0, 0, 0},
+			{0, 0, 0},
+			{0, 0, 0},
+		},
+		{
+			{1, 0, 0},
+			{0, 0, 0},
+			{0, 0, 0},
+		},
+	}
+	tt := []struct {
+		volume     Volume
+		opts       VolumeOptions
+		wantShapes int
+		wantCount  int
+		err        error
+	}{
+		{volume: v, wantShapes: 2, wantCount: 2},
+		{volume: v, opts: VolumeOptions{Plane: [3]bool{false, false, true}}, wantShapes: 1, wantCount: 3},
+		{volume: v, opts: VolumeOptions{Connectivity: TwentySixWay}, wantShapes: 1, wantCount: 1},
+		{volume: v, opts: VolumeOptions{Connectivity: TwentySixWay, Plane: [3]bool{false, false, true}}, wantShapes: 2, wantCount: 2},
+		{volume: Volume{}, err: errorNoRows},
+		{volume: Volume{{{1}}, {{1, 0}}}, err: errorRagged},
+	}
+	for i, tc := range tt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			s, err := NewVolume(context.Background(), tc.volume, tc.opts)
+			if err != tc.err {
+				t.Fatalf("want error %v got %v", tc.err, err)
+			}
+			if err != nil {
+				return
+			}
+			if len(s.shapes) != tc.wantShapes || len(s.components) != tc.wantCount {
+				t.Fatalf("want %d shapes and %d components got %d and %d", tc.wantShapes, tc.wantCount, len(s.shapes), len(s.components))
+			}
+		})
+	}
+}
+
+func TestPrintVolume(t *testing.T) {
+	s, err := NewVolume(context.Background(), Volume{
+		{{1, 1}, {0, 0}},
+		{{0, 1}, {0, 0}},
+	}, VolumeOptions{Plane: [3]bool{true, true, true}})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	var b bytes.Buffer
+	s.Print(&b)
+	want := "    XX\n\n     X\n------\n"
+	if b.String() != want {
+		t.Fatalf("want\n%q\ngot\n%q", want, b.String())
+	}
+}
+
+func TestMesh(t *testing.T) {
+	s, err := NewVolume(context.Background(), Volume{
+		{{1, 1, 0}, {0, 1, 0}},
+		{{0, 1, 0}, {0, 0, 0}},
+		{{0, 0, 0}, {0, 0, 2}},
+	}, VolumeOptions{Plane: [3]bool{true, true, true}})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	surfaces := s.surfaces()
+	if len(surfaces) != 2 || len(surfaces[0]) != 4*6-2*3 || len(surfaces[1]) != 6 {
+		t.Fatalf("unexpected faces %v", surfaces)
+	}
+	// Every edge of a closed surface with consistent winding is walked once each way.
+	for i, faces := range surfaces {
+		edges := make(map[[2]voxel]int)
+		for _, f := range faces {
+			for k := range f.corners {
+				edges[[2]voxel{f.corners[k], f.corners[(k+1)%4]}]++
+			}
+		}
+		for e, n := range edges {
+			if n != 1 || edges[[2]voxel{e[1], e[0]}] != 1 {
+				t.Fatalf("shape %d edge %v is not shared by two faces", i, e)
+			}
+		}
+	}
+
+	var obj, stl bytes.Buffer
+	if err := s.WriteOBJ(&obj); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if strings.Count(obj.String(), "\nf ") != 24 || strings.Count(obj.String(), "o shape") != 2 {
+		t.Fatalf("unexpected OBJ\n%s", obj.String())
+	}
+	if err := s.WriteSTL(&stl); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if strings.Count(stl.String(), "facet normal") != 48 || !strings.HasSuffix(stl.String(), "endsolid shapes\n") {
+		t.Fatalf("unexpected STL\n%s", stl.String())
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io"
+	"io/ioutil"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/murphybytes/shapes/search"
+)
+
+const errorMethod = errorType("method not allowed, use POST")
+const errorMediaType = errorType("unsupported content type, use text/plain, application/json, image/png, image/gif or image/jpeg")
+const errorBodyTooLarge = errorType("request body too large")
+
+// server answers shape searches over HTTP.
+type server struct {
+	// maxBytes is the largest request body accepted.
+	maxBytes int64
+	// maxCells is the largest grid accepted, counted in cells.
+	maxCells int
+	// timeout bounds the time spent answering a request.
+	timeout time.Duration
+}
+
+func (c cli) serve(fs *flag.FlagSet, args []string) error {
+	addr := fs.String("addr", ":8080", "address to listen on")
+	maxBytes := fs.Int64("max-bytes", 1<<20, "largest request body accepted")
+	maxCells := fs.Int("max-cells", 1<<20, "largest grid accepted, in cells")
+	timeout := fs.Duration("timeout", 10*time.Second, "time allowed to answer a request")
+	if err := c.parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	sv := server{maxBytes: *maxBytes, maxCells: *maxCells, timeout: *timeout}
+	hs := &http.Server{
+		Addr:              *addr,
+		Handler:           sv.routes(),
+		ReadHeaderTimeout: *timeout,
+		ReadTimeout:       *timeout,
+		WriteTimeout:      2 * *timeout,
+		IdleTimeout:       time.Minute,
+	}
+	log.Printf("listening on %s", *addr)
+	return hs.ListenAndServe()
+}
+
+// routes serves POST /search, which takes a grid in the body and options in the query string and responds with the
+// search result as JSON, and GET /healthz.
+func (sv server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/search", sv.handleSearch)
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok\n")
+	})
+	return http.TimeoutHandler(mux, sv.timeout, `{"error":"request timed out"}`)
+}
+
+func (sv server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, errorMethod)
+		return
+	}
+	opts, f, err := parseOptions(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, sv.maxBytes+1))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if int64(len(body)) > sv.maxBytes {
+		writeError(w, http.StatusRequestEntityTooLarge, errorBodyTooLarge)
+		return
+	}
+
+	grid, err := sv.parseBody(r.Header.Get("Content-Type"), body, f)
+	switch err {
+	case nil:
+	case errorMediaType:
+		writeError(w, http.StatusUnsupportedMediaType, err)
+		return
+	case errorGridTooLarge:
+		writeError(w, http.StatusRequestEntityTooLarge, err)
+		return
+	default:
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if len(grid)*len(grid[0]) > sv.maxCells {
+		writeError(w, http.StatusRequestEntityTooLarge, errorGridTooLarge)
+		return
+	}
+
+	// The timeout handler cancels the request's context and answers for us when time runs out.
+	s, err := search.NewWithContext(r.Context(), grid, opts)
+	if err != nil && r.Context().Err() != nil {
+		log.Printf("search abandoned %q", err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.Result())
+}
+
+func (sv server) parseBody(contentType string, body []byte, f format) ([][]int, error) {
+	mediaType := "text/plain"
+	if contentType != "" {
+		var err error
+		if mediaType, _, err = mime.ParseMediaType(contentType); err != nil {
+			return nil, errorMediaType
+		}
+	}
+	switch mediaType {
+	case "text/plain":
+		return parseText(bytes.NewReader(body), f)
+	case "application/json":
+		return parseJSON(bytes.NewReader(body), f)
+	case "image/png", "image/gif", "image/jpeg":
+		return parseImage(bytes.NewReader(body), sv.maxCells)
+	}
+	return nil, errorMediaType
+}
+
+// parseOptions reads search options from query parameters named after the command line flags.
+func parseOptions(q url.Values) (search.Options, format, error) {
+	var opts search.Options
+	var f format
+	for name, values := range q {
+		v := values[0]
+		var err error
+		switch name {
+		case "topology":
+			err = opts.Topology.UnmarshalText([]byte(v))
+		case "connectivity":
+			err = opts.Connectivity.UnmarshalText([]byte(v))
+		case "equivalence":
+			err = opts.Equivalence.UnmarshalText([]byte(v))
+		case "lattice":
+			err = opts.Lattice.UnmarshalText([]byte(v))
+		case "filter":
+			err = opts.Filter.UnmarshalText([]byte(v))
+		case "background":
+			opts.Background, err = strconv.Atoi(v)
+		case "across":
+			opts.AcrossValues, err = strconv.ParseBool(v)
+		case "fill-holes":
+			opts.FillHoles, err = strconv.ParseBool(v)
+		case "parallel":
+			opts.Parallel, err = strconv.ParseBool(v)
+		case "labels":
+			f.labels, err = strconv.ParseBool(v)
+		default:
+			err = errorType("unknown option " + strconv.Quote(name))
+		}
+		if err != nil {
+			return opts, f, err
+		}
+	}
+	return opts, f, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("writing response %q", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/murphybytes/shapes/search"
+)
+
+func pngGrid(t *testing.T, grid [][]int) []byte {
+	img := image.NewGray(image.Rect(0, 0, len(grid[0]), len(grid)))
+	for y, row := range grid {
+		for x, v := range row {
+			img.SetGray(x, y, color.Gray{Y: uint8(255 * (1 - v))})
+		}
+	}
+	var b bytes.Buffer
+	if err := png.Encode(&b, img); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	return b.Bytes()
+}
+
+func emptyGrid(rows, cols int) [][]int {
+	grid := make([][]int, rows)
+	for i := range grid {
+		grid[i] = make([]int, cols)
+	}
+	return grid
+}
+
+func TestServeSearch(t *testing.T) {
+	ts := httptest.NewServer(server{maxBytes: 1024, maxCells: 100, timeout: time.Second}.routes())
+	defer ts.Close()
+
+	tt := []struct {
+		method, query, contentType string
+		body                       []byte
+		wantStatus                 int
+		wantShapes, wantCount      int
+	}{
+		{
+			method:      http.MethodPost,
+			contentType: "text/plain",
+			body:        []byte("1 0 0 1\n0 0 0 0\n0 1 0 0\n"),
+			wantStatus:  http.StatusOK,
+			wantShapes:  2,
+			wantCount:   1,
+		},
+		{
+			method:      http.MethodPost,
+			query:       "?topology=plane",
+			contentType: "text/plain; charset=utf-8",
+			body:        []byte("1 0 0 1\n0 0 0 0\n0 1 0 0\n"),
+			wantStatus:  http.StatusOK,
+			wantShapes:  1,
+			wantCount:   3,
+		},
+		{
+			method:      http.MethodPost,
+			query:       "?topology=plane&connectivity=8&labels=true",
+			contentType: "application/json",
+			body:        []byte(`{"grid": [[2, 0, 0], [0, 2, 0], [0, 0, 0]]}`),
+			wantStatus:  http.StatusOK,
+			wantShapes:  1,
+			wantCount:   1,
+		},
+		{
+			method:      http.MethodPost,
+			contentType: "image/png",
+			body: pngGrid(t, [][]int{
+				{1, 1, 0, 0},
+				{0, 0, 0, 0},
+				{0, 1, 1, 0},
+				{0, 0, 0, 0},
+			}),
+			wantStatus: http.StatusOK,
+			wantShapes: 1,
+			wantCount:  2,
+		},
+		{
+			method:      http.MethodPost,
+			query:       "?lattice=hex",
+			contentType: "text/plain",
+			body:        []byte("0 1 0 0\n0 1 0 0\n0 0 0 0\n0 0 0 0\n"),
+			wantStatus:  http.StatusOK,
+			wantShapes:  1,
+			wantCount:   1,
+		},
+		{
+			method:      http.MethodPost,
+			query:       "?topology=plane&filter=!edge",
+			contentType: "text/plain",
+			body:        []byte("1 0 0 0\n0 1 0 0\n0 0 0 0\n1 0 0 0\n"),
+			wantStatus:  http.StatusOK,
+			wantShapes:  1,
+			wantCount:   1,
+		},
+		{
+			method:      http.MethodPost,
+			query:       "?equivalence=shear",
+			contentType: "text/plain",
+			body:        []byte("1\n"),
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			method:      http.MethodPost,
+			query:       "?color=blue",
+			contentType: "text/plain",
+			body:        []byte("1\n"),
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			method:      http.MethodPost,
+			contentType: "text/plain",
+			body:        []byte("1 0\n1\n"),
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			method:      http.MethodPost,
+			contentType: "text/plain",
+			body:        []byte("1 0 7\n"),
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			method:      http.MethodPost,
+			contentType: "application/xml",
+			body:        []byte("<grid/>"),
+			wantStatus:  http.StatusUnsupportedMediaType,
+		},
+		{
+			method:      http.MethodPost,
+			contentType: "text/plain",
+			body:        []byte(strings.Repeat("0 ", 600)),
+			wantStatus:  http.StatusRequestEntityTooLarge,
+		},
+		{
+			method:      http.MethodPost,
+			contentType: "text/plain",
+			body:        []byte(strings.Repeat("0 ", 101)),
+			wantStatus:  http.StatusRequestEntityTooLarge,
+		},
+		{
+			method:      http.MethodPost,
+			contentType: "image/png",
+			body:        pngGrid(t, emptyGrid(11, 11)),
+			wantStatus:  http.StatusRequestEntityTooLarge,
+		},
+		{
+			method:     http.MethodGet,
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for i, tc := range tt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			req, err := http.NewRequest(tc.method, ts.URL+"/search"+tc.query, bytes.NewReader(tc.body))
+			if err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tc.wantStatus {
+				t.Fatalf("want status %d got %d", tc.wantStatus, resp.StatusCode)
+			}
+			if resp.StatusCode != http.StatusOK {
+				var e map[string]string
+				if err := json.NewDecoder(resp.Body).Decode(&e); err != nil || e["error"] == "" {
+					t.Fatalf("want an error message got %v %v", e, err)
+				}
+				return
+			}
+			var got search.Result
+			if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			if len(got.Shapes) != tc.wantShapes {
+				t.Fatalf("want %d shapes got %d", tc.wantShapes, len(got.Shapes))
+			}
+			if got.Shapes[0].Count != tc.wantCount {
+				t.Fatalf("want count %d got %d", tc.wantCount, got.Shapes[0].Count)
+			}
+		})
+	}
+}
+
+func TestServeHealth(t *testing.T) {
+	w := httptest.NewRecorder()
+	server{timeout: time.Second}.routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "ok\n" {
+		t.Fatalf("got %d %q", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,14 @@
+package main
+
+const errorNoTerminal = errorType("standard input is not a terminal")
+
+// Escape sequences understood by ANSI terminals.
+const (
+	clearScreen = "\x1b[H\x1b[2J"
+	altScreen   = "\x1b[?1049h"
+	mainScreen  = "\x1b[?1049l"
+	hideCursor  = "\x1b[?25l"
+	showCursor  = "\x1b[?25h"
+	reverse     = "\x1b[7m"
+	reset       = "\x1b[0m"
+)
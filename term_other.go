@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd,!dragonfly
+
+package main
+
+func isTerminal(fd int) bool { return false }
+
+func makeRaw(fd int) (func() error, error) { return nil, errorNoTerminal }
+
+func terminalSize(fd int) (rows, cols int, err error) { return 0, 0, errorNoTerminal }
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const errorTranscript = errorType("transcript lines start with $, >, ?, <, !, = or #")
+const errorNoArgs = errorType("transcript has no $ line of arguments")
+const errorReplay = errorType("replay differs from the transcript")
+const errorQuote = errorType("unterminated quote in transcript arguments")
+
+// record runs the command given after the transcript's name as it would run on its own, and then writes the
+// session to the transcript. Recording succeeds whatever the exit code of the command, which the transcript holds.
+func (c cli) record(fs *flag.FlagSet, args []string) error {
+	// The flags of the command recorded are its own, so only the arguments up to the transcript's name are parsed.
+	n := len(args)
+	for i, a := range args {
+		if !strings.HasPrefix(a, "-") {
+			n = i + 1
+			break
+		}
+	}
+	if err := c.parse(fs, args[:n], 1); err != nil {
+		return err
+	}
+	t := &transcript{}
+	t.run(c.stdin, c.stdout, c.stderr, args[n:])
+	return ioutil.WriteFile(fs.Arg(0), []byte(t.String()), 0644)
+}
+
+// replay runs the command of a transcript again with the input it holds, writing the output as it goes, and checks
+// the session matches the transcript.
+func (c cli) replay(fs *flag.FlagSet, args []string) error {
+	update := fs.Bool("update", false, "write the new session to the transcript instead of checking it, keeping the comments it starts with")
+	if err := c.parse(fs, args, 1); err != nil {
+		return err
+	}
+	b, err := ioutil.ReadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	want, err := readTranscript(bytes.NewReader(b))
+	if err != nil {
+		return inputError{err}
+	}
+	t, err := replay(want, c.stdout, c.stderr)
+	if err != nil {
+		return inputError{err}
+	}
+	if *update {
+		return ioutil.WriteFile(fs.Arg(0), []byte(header(b)+t.String()), 0644)
+	}
+	return t.check(want)
+}
+
+// transcript is a session with the program written as text, a line for each line of input or output, starting
+// with what it is:
+//
+//	$ the arguments the program was run with
+//	> a line written to standard output
+//	? output left unfinished when input was read, as a prompt is
+//	< a line of input read
+//	! a line written to standard error
+//	= the exit code
+//
+// Lines starting with # are comments.
+type transcript struct {
+	mu    sync.Mutex
+	lines []string
+	// pending is output of the kind not yet ended by a newline, and input the input read not yet ended by one.
+	kind    byte
+	pending []byte
+	input   []byte
+}
+
+// run runs the program with args, passing its input and output through while writing them down.
+func (t *transcript) run(stdin io.Reader, stdout, stderr io.Writer, args []string) {
+	t.add('$', joinArgs(args))
+	code := cli{
+		stdin:  transcriptReader{t, stdin},
+		stdout: transcriptWriter{t, '>', stdout},
+		stderr: transcriptWriter{t, '!', stderr},
+	}.run(args)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.flush()
+	if len(t.input) > 0 {
+		t.add('<', string(t.input))
+	}
+	t.add('=', strconv.Itoa(code))
+}
+
+func (t *transcript) add(kind byte, s string) {
+	if s == "" {
+		t.lines = append(t.lines, string(kind))
+		return
+	}
+	t.lines = append(t.lines, string(kind)+" "+s)
+}
+
+// output writes down output of a kind a line at a time.
+func (t *transcript) output(kind byte, p []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if kind != t.kind {
+		t.flush()
+		t.kind = kind
+	}
+	t.pending = append(t.pending, p...)
+	for i := bytes.IndexByte(t.pending, '\n'); i >= 0; i = bytes.IndexByte(t.pending, '\n') {
+		t.add(kind, string(t.pending[:i]))
+		t.pending = t.pending[i+1:]
+	}
+}
+
+// flush writes down output not ended by a newline.
+func (t *transcript) flush() {
+	if len(t.pending) == 0 {
+		return
+	}
+	kind := t.kind
+	if kind == '>' {
+		kind = '?'
+	}
+	t.add(kind, string(t.pending))
+	t.pending = nil
+}
+
+// read writes down input a line at a time, and the last line even without a newline once the input has ended.
+func (t *transcript) read(p []byte, end bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.flush()
+	t.input = append(t.input, p...)
+	for i := bytes.IndexByte(t.input, '\n'); i >= 0; i = bytes.IndexByte(t.input, '\n') {
+		t.add('<', strings.TrimSuffix(string(t.input[:i]), "\r"))
+		t.input = t.input[i+1:]
+	}
+	if end && len(t.input) > 0 {
+		t.add('<', string(t.input))
+		t.input = nil
+	}
+}
+
+func (t *transcript) String() string {
+	return strings.Join(t.lines, "\n") + "\n"
+}
+
+type transcriptWriter struct {
+	t    *transcript
+	kind byte
+	w    io.Writer
+}
+
+func (tw transcriptWriter) Write(p []byte) (int, error) {
+	tw.t.output(tw.kind, p)
+	return tw.w.Write(p)
+}
+
+type transcriptReader struct {
+	t *transcript
+	r io.Reader
+}
+
+func (tr transcriptReader) Read(p []byte) (int, error) {
+	n, err := tr.r.Read(p)
+	tr.t.read(p[:n], err == io.EOF)
+	return n, err
+}
+
+// readTranscript reads the lines of a transcript, leaving out comments and blank lines.
+func readTranscript(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<24)
+	for scanner.Scan() {
+		l := scanner.Text()
+		if l == "" || l[0] == '#' {
+			continue
+		}
+		if !strings.ContainsAny(l[:1], "$>?<!=") || len(l) > 1 && l[1] != ' ' {
+			return nil, fmt.Errorf("%s, found %q", errorTranscript, l)
+		}
+		lines = append(lines, l)
+	}
+	return lines, scanner.Err()
+}
+
+// header returns the comment lines a transcript starts with.
+func header(b []byte) string {
+	var h strings.Builder
+	for _, l := range strings.SplitAfter(string(b), "\n") {
+		if !strings.HasPrefix(l, "#") {
+			break
+		}
+		h.WriteString(l)
+	}
+	return h.String()
+}
+
+// replay runs the program again with the arguments and input of a transcript's lines, writing its output to
+// stdout and stderr, and returns the transcript of the new session.
+func replay(lines []string, stdout, stderr io.Writer) (*transcript, error) {
+	var args []string
+	var in strings.Builder
+	found := false
+	for _, l := range lines {
+		var err error
+		switch l[0] {
+		case '$':
+			if !found {
+				args, err = splitArgs(text(l))
+				found = true
+			}
+		case '<':
+			in.WriteString(text(l) + "\n")
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !found {
+		return nil, errorNoArgs
+	}
+	t := &transcript{}
+	t.run(strings.NewReader(in.String()), stdout, stderr, args)
+	return t, nil
+}
+
+// text is a transcript line without the character that starts it.
+func text(l string) string {
+	return strings.TrimPrefix(l[1:], " ")
+}
+
+// check returns an error naming the first line where a session differs from the lines of a transcript.
+func (t *transcript) check(want []string) error {
+	for i := 0; i < len(want) || i < len(t.lines); i++ {
+		var w, g string
+		if i < len(want) {
+			w = want[i]
+		}
+		if i < len(t.lines) {
+			g = t.lines[i]
+		}
+		if w != g {
+			return fmt.Errorf("%s: want %q got %q", errorReplay, w, g)
+		}
+	}
+	return nil
+}
+
+// joinArgs writes arguments separated by spaces, quoting those that are empty or hold spaces or quotes.
+func joinArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		quoted[i] = a
+		if a == "" || strings.ContainsAny(a, " \t\"\\") || !strconv.CanBackquote(a) {
+			quoted[i] = strconv.Quote(a)
+		}
+	}
+	return strings.Join(quoted, " ")
+}
+
+// splitArgs reads back the arguments written by joinArgs.
+func splitArgs(s string) ([]string, error) {
+	var args []string
+	for s = strings.TrimLeft(s, " "); s != ""; s = strings.TrimLeft(s, " ") {
+		end := strings.IndexByte(s, ' ')
+		if s[0] == '"' {
+			// The closing quote is the first one not escaped by a backslash.
+			end = -1
+			for i := 1; i < len(s); i++ {
+				if s[i] == '\\' {
+					i++
+				} else if s[i] == '"' {
+					end = i + 1
+					break
+				}
+			}
+			if end < 0 {
+				return nil, errorQuote
+			}
+			a, err := strconv.Unquote(s[:end])
+			if err != nil {
+				return nil, err
+			}
+			args, s = append(args, a), s[end:]
+			continue
+		}
+		if end < 0 {
+			end = len(s)
+		}
+		args, s = append(args, s[:end]), s[end:]
+	}
+	return args, nil
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestTranscripts replays each transcript in testdata/transcripts and checks the prompts, answers and results are
+// the same. Record a new one with shapes record testdata/transcripts/name.txt followed by a command.
+func TestTranscripts(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "transcripts", "*.txt"))
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if len(files) == 0 {
+		t.Fatal("want transcripts")
+	}
+	for _, name := range files {
+		t.Run(filepath.Base(name), func(t *testing.T) {
+			f, err := os.Open(name)
+			if err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			defer f.Close()
+			want, err := readTranscript(f)
+			if err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			got, err := replay(want, ioutil.Discard, ioutil.Discard)
+			if err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			if err := got.check(want); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestRecord(t *testing.T) {
+	tt := []struct {
+		args  []string
+		stdin string
+		want  []string
+	}{
+		{
+			args:  []string{"find", "-format", "text", "-glyphs", "X "},
+			stdin: "1 1\n",
+			want:  []string{`$ find -format text -glyphs "X "`, "< 1 1", ">     XX", "> ------", "= 0"},
+		},
+		{
+			args:  []string{"find", "-format", "paste"},
+			stdin: "1\n\nX",
+			want: []string{
+				"$ find -format paste",
+				"> Paste the grid, one row per line of space separated ones or zeros, then a blank line.",
+				"< 1",
+				"<",
+				"> You entered 1 rows and 1 columns.",
+				"> 1: 1",
+				"? Continue (C) Edit a row (E) Retry (R) Cancel (X)? ",
+				"< X",
+				"! shapes find: user terminated",
+				"= 4",
+			},
+		},
+	}
+	for i, tc := range tt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var out strings.Builder
+			s := &transcript{}
+			s.run(strings.NewReader(tc.stdin), &out, ioutil.Discard, tc.args)
+			if !reflect.DeepEqual(s.lines, tc.want) {
+				t.Fatalf("want\n%q\ngot\n%q", tc.want, s.lines)
+			}
+			if !strings.Contains(out.String(), "X") {
+				t.Fatalf("want output passed through, got %q", out.String())
+			}
+		})
+	}
+}
+
+func TestReadTranscript(t *testing.T) {
+	tt := []struct {
+		input string
+		want  []string
+		err   bool
+	}{
+		{input: "# comment\n$ find\n\n< 1 1\n<\n= 0\n", want: []string{"$ find", "< 1 1", "<", "= 0"}},
+		{input: "$ find\n1 1\n", err: true},
+		{input: "$ find\n<1 1\n", err: true},
+	}
+	for i, tc := range tt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			got, err := readTranscript(strings.NewReader(tc.input))
+			if (err != nil) != tc.err {
+				t.Fatalf("want error %v got %v", tc.err, err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("want %q got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestReplay(t *testing.T) {
+	want := []string{"$ find -format text", "< 1 0", ">     X", "> -----", "= 0"}
+	got, err := replay(want, ioutil.Discard, ioutil.Discard)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if err := got.check(want); err != nil {
+		t.Fatal(err)
+	}
+	changed := append(append([]string(nil), want[:2]...), ">     XX", "> ------", "= 0")
+	if err := got.check(changed); err == nil || !strings.Contains(err.Error(), `want ">     XX" got ">     X"`) {
+		t.Fatalf("want the first difference reported, got %v", err)
+	}
+	if _, err := replay([]string{"< 1"}, ioutil.Discard, ioutil.Discard); err != errorNoArgs {
+		t.Fatalf("want error %v got %v", errorNoArgs, err)
+	}
+}
+
+func TestSplitArgs(t *testing.T) {
+	tt := [][]string{
+		{"find", "-format", "text"},
+		{"find", "-glyphs", "# ", "-comment", ""},
+		{"find", "-in", `a "quoted" name\`},
+		nil,
+	}
+	for i, args := range tt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			got, err := splitArgs(joinArgs(args))
+			if err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			if !reflect.DeepEqual(got, args) {
+				t.Fatalf("want %q got %q", args, got)
+			}
+		})
+	}
+	if _, err := splitArgs(`find "open`); err != errorQuote {
+		t.Fatalf("want error %v got %v", errorQuote, err)
+	}
+}
+
+func TestRecordReplayCommands(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shapes")
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "session.txt")
+
+	if code, stdout, _ := runCLI([]string{"record", name, "find", "-format", "text"}, "1 0\n"); code != exitOK || stdout != "    X\n-----\n" {
+		t.Fatalf("want the session passed through, got exit code %d and %q", code, stdout)
+	}
+	if code, stdout, _ := runCLI([]string{"replay", name}, ""); code != exitOK || stdout != "    X\n-----\n" {
+		t.Fatalf("want the session replayed, got exit code %d and %q", code, stdout)
+	}
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	recorded := string(b)
+	changed := "# a grid of one cell\n" + strings.Replace(recorded, "-----", "------", 1)
+	if err := ioutil.WriteFile(name, []byte(changed), 0600); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if code, _, stderr := runCLI([]string{"replay", name}, ""); code != exitFailure || !strings.Contains(stderr, "replay differs") {
+		t.Fatalf("want the difference reported, got exit code %d and %q", code, stderr)
+	}
+	if code, _, _ := runCLI([]string{"replay", "-update", name}, ""); code != exitOK {
+		t.Fatalf("want the transcript updated, got exit code %d", code)
+	}
+	if b, err = ioutil.ReadFile(name); err != nil || string(b) != "# a grid of one cell\n"+recorded {
+		t.Fatalf("want the transcript rewritten with its comment, got %q %v", b, err)
+	}
+	if code, _, _ := runCLI([]string{"record", name}, ""); code != exitOK {
+		t.Fatalf("want a session with no command recorded, got exit code %d", code)
+	}
+	if code, _, _ := runCLI([]string{"record"}, ""); code != exitUsage {
+		t.Fatalf("want a usage error, got exit code %d", code)
+	}
+}
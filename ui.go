@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
@@ -14,8 +15,45 @@ func (et errorType) Error() string { return string(et) }
 const errorUserTerminated = errorType("user terminated")
 const errorChoices = errorType("choice string format")
 const errorIllegalColumn = errorType("column value must be one or zero")
+const errorNegativeColumn = errorType("column value must not be negative")
+const errorRowNumber = errorType("no row with that number")
 
-func readGrid(r io.Reader, w io.Writer) ([][]int, error) {
+// format controls how grid rows are read.
+type format struct {
+	// labels accepts any non-negative integer as a cell value instead of only ones and zeros.
+	labels bool
+	// set and unset are the characters that stand for set and empty cells in compact text, as well as digits.
+	set, unset string
+	// comment starts the lines that text is read without, if not empty.
+	comment string
+}
+
+// glyphs returns the characters of set and empty cells in compact text, # and . unless the format gives others.
+func (f format) glyphs() (set, unset string) {
+	set, unset = f.set, f.unset
+	if set == "" {
+		set = "#"
+	}
+	if unset == "" {
+		unset = "."
+	}
+	return set, unset
+}
+
+// skip reports whether a line of text is blank or a comment.
+func (f format) skip(line string) bool {
+	line = strings.TrimSpace(line)
+	return line == "" || f.comment != "" && strings.HasPrefix(line, f.comment)
+}
+
+func (f format) describe() string {
+	if f.labels {
+		return "space separated non-negative integers"
+	}
+	return "space separated ones or zeros"
+}
+
+func readGrid(r io.Reader, w io.Writer, f format) ([][]int, error) {
 	rows, cols, err := getDimensions(r, w)
 	if err != nil {
 		return nil, err
@@ -23,7 +61,7 @@ func readGrid(r io.Reader, w io.Writer) ([][]int, error) {
 
 	var grid [][]int
 	for i := 0; i < rows; i++ {
-		row, err := getRow(r, w, cols)
+		row, err := getRow(r, w, cols, f)
 		if err != nil {
 			return nil, err
 		}
@@ -33,6 +71,123 @@ func readGrid(r io.Reader, w io.Writer) ([][]int, error) {
 	return grid, nil
 }
 
+// pasteGrid reads a whole grid pasted in one go, ended by a blank line or the end of input, shows it back with its
+// size and asks once whether to use it. Rows can be typed again by number before going on.
+func pasteGrid(r io.Reader, w io.Writer, f format) ([][]int, error) {
+	for {
+		fmt.Fprintf(w, "Paste the grid, one row per line of %s, then a blank line.\n", f.describe())
+		grid, err := readBlock(r, f)
+		if err != nil {
+			choice, err := prompt(r, w, fmt.Sprintf("Error: %q Retry (R) Cancel (X)? ", err))
+			if err != nil {
+				return nil, err
+			}
+			if choice == "X" {
+				return nil, errorUserTerminated
+			}
+			continue
+		}
+	confirm:
+		for {
+			showGrid(w, grid)
+			choice, err := prompt(r, w, "Continue (C) Edit a row (E) Retry (R) Cancel (X)? ")
+			if err != nil {
+				return nil, err
+			}
+			switch choice {
+			case "C":
+				return grid, nil
+			case "X":
+				return nil, errorUserTerminated
+			case "E":
+				if err := editRow(r, w, grid, f); err != nil {
+					return nil, err
+				}
+			case "R":
+				break confirm
+			}
+		}
+	}
+}
+
+// readBlock reads rows up to a blank line or the end of input. Blank lines before the first row are skipped.
+func readBlock(r io.Reader, f format) ([][]int, error) {
+	var b strings.Builder
+	for {
+		line, err := readLine(r)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if strings.TrimSpace(line) != "" {
+			b.WriteString(line + "\n")
+		} else if b.Len() > 0 {
+			break
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	return parseText(strings.NewReader(b.String()), f)
+}
+
+// readLine reads up to the end of a line a byte at a time, so nothing past it is taken from r before the prompts
+// that follow read it.
+func readLine(r io.Reader) (string, error) {
+	var line []byte
+	b := make([]byte, 1)
+	for {
+		n, err := r.Read(b)
+		if n == 1 && b[0] == '\n' {
+			return strings.TrimSuffix(string(line), "\r"), nil
+		}
+		if n == 1 {
+			line = append(line, b[0])
+		}
+		if err != nil {
+			return string(line), err
+		}
+	}
+}
+
+// showGrid writes a grid back with its size and the number of each row.
+func showGrid(w io.Writer, grid [][]int) {
+	fmt.Fprintf(w, "You entered %d rows and %d columns.\n", len(grid), len(grid[0]))
+	width := len(strconv.Itoa(len(grid)))
+	for i, row := range grid {
+		fmt.Fprintf(w, "%*d: %s\n", width, i+1, strings.Trim(fmt.Sprint(row), "[]"))
+	}
+}
+
+// editRow asks for the number of a row and then for the row to put in its place.
+func editRow(r io.Reader, w io.Writer, grid [][]int, f format) error {
+	for {
+		fmt.Fprintf(w, "Enter the number of the row to edit, 1 to %d. ", len(grid))
+		line, err := readLine(r)
+		if err == io.EOF {
+			return err
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(line))
+		if err == nil && (n < 1 || n > len(grid)) {
+			err = errorRowNumber
+		}
+		if err == nil {
+			fmt.Fprintf(w, "Enter a %d element row containing %s\n", len(grid[0]), f.describe())
+			var row []int
+			if row, err = readRow(r, len(grid[0]), f); err == nil {
+				grid[n-1] = row
+				return nil
+			}
+		}
+		choice, err := prompt(r, w, fmt.Sprintf("Error: %q Retry (R) Cancel (X)? ", err))
+		if err != nil {
+			return err
+		}
+		if choice == "X" {
+			return errorUserTerminated
+		}
+	}
+}
+
 // Substrings that are surrounded by parenthesis are the valid response.  For example (A) with yield a valid response of
 // A as in "Choose (A) or (B)"
 func parseChoices(prompt string) ([]string, error) {
@@ -59,7 +214,9 @@ func prompt(r io.Reader, w io.Writer, prompt string) (string, error) {
 			return "", err
 		}
 		var response string
-		fmt.Fscanln(r, &response)
+		if _, err := fmt.Fscanln(r, &response); err == io.EOF || err == io.ErrUnexpectedEOF {
+			return "", err
+		}
 		if validChoice(response, choices...) {
 			return response, nil
 		}
@@ -100,7 +257,7 @@ func getDimensions(r io.Reader, w io.Writer) (rows, cols int, err error) {
 
 }
 
-func readRow(rdr io.Reader, cols int) ([]int, error) {
+func readRow(rdr io.Reader, cols int, f format) ([]int, error) {
 	row := make([]int, cols)
 	var read []interface{}
 	for i := 0; i < cols; i++ {
@@ -111,25 +268,21 @@ func readRow(rdr io.Reader, cols int) ([]int, error) {
 		return nil, err
 	}
 
-	for i := 0; i < cols; i++ {
-		switch row[i] {
-		case 0, 1:
-		default:
-			return nil, errorIllegalColumn
-		}
+	if err := f.check(row); err != nil {
+		return nil, err
 	}
 
 	return row, nil
 }
 
-func getRow(r io.Reader, w io.Writer, cols int) ([]int, error) {
+func getRow(r io.Reader, w io.Writer, cols int, f format) ([]int, error) {
 	for {
 		var entries []int
 		var err error
 
 		for {
-			_, _ = fmt.Fprintf(w, "Enter a %d element row containing space separated ones or zeros\n", cols)
-			entries, err = readRow(r, cols)
+			_, _ = fmt.Fprintf(w, "Enter a %d element row containing %s\n", cols, f.describe())
+			entries, err = readRow(r, cols, f)
 			if err != nil {
 				choice, err := prompt(r, w, fmt.Sprintf("Error: %q Retry (R) Cancel (X)? ", err))
 				if err != nil {
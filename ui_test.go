@@ -3,6 +3,8 @@ package main
 import (
 	"bytes"
 	"errors"
+	"io"
+	"reflect"
 	"strconv"
 	"strings"
 	"testing"
@@ -115,21 +117,24 @@ func TestInputRowsCols(t *testing.T) {
 
 func TestReadRow(t *testing.T) {
 	tt := []struct {
-		input string
-		want  []int
-		err   bool
-		cols  int
+		input  string
+		want   []int
+		err    bool
+		cols   int
+		labels bool
 	}{
-		{"0 1 0 0\n", []int{0, 1, 0, 0}, false, 4},
-		{"bob 0 0 0\n", nil, true, 4},
-		{"0 20 0 0\n", nil, true, 4},
-		{"0 1 0 0 0\n", nil, true, 4},
-		{"0 1 0 0\n", nil, true, 5},
+		{"0 1 0 0\n", []int{0, 1, 0, 0}, false, 4, false},
+		{"bob 0 0 0\n", nil, true, 4, false},
+		{"0 20 0 0\n", nil, true, 4, false},
+		{"0 1 0 0 0\n", nil, true, 4, false},
+		{"0 1 0 0\n", nil, true, 5, false},
+		{"0 20 0 3\n", []int{0, 20, 0, 3}, false, 4, true},
+		{"0 -2 0 3\n", nil, true, 4, true},
 	}
 	for i, tc := range tt {
 		t.Run(strconv.Itoa(i), func(t *testing.T) {
 			rdr := bytes.NewBufferString(tc.input)
-			got, err := readRow(rdr, tc.cols)
+			got, err := readRow(rdr, tc.cols, format{labels: tc.labels})
 			if tc.err && err == nil {
 				t.Fatalf("expected error")
 			}
@@ -194,7 +199,7 @@ func TestGetRow(t *testing.T) {
 	for i, tc := range tt {
 		t.Run(strconv.Itoa(i), func(t *testing.T) {
 			var w bytes.Buffer
-			got, err := getRow(bytes.NewBufferString(tc.input), &w, tc.cols)
+			got, err := getRow(bytes.NewBufferString(tc.input), &w, tc.cols, format{})
 			if err != tc.err {
 				t.Fatalf("unexpected error value %v", err)
 			}
@@ -217,7 +222,7 @@ func TestReadGrid(t *testing.T) {
 	}
 	input := "4 6\nC\n1 0 1 0 1 0\nC\n0 1 0 1 0 1\nC\n1 1 1 1 1 1\nC\n1 1 1 0 0 0\nC\n"
 	var w bytes.Buffer
-	got, err := readGrid(bytes.NewBufferString(input), &w)
+	got, err := readGrid(bytes.NewBufferString(input), &w, format{})
 	if err != nil {
 		t.Fatal("unexpected", err)
 	}
@@ -230,6 +235,53 @@ func TestReadGrid(t *testing.T) {
 	}
 }
 
+func TestPasteGrid(t *testing.T) {
+	tt := []struct {
+		input      string
+		want       [][]int
+		wantOutput string
+		err        error
+	}{
+		{
+			input: "1 0 1\n0 1 1\n\nC\n",
+			want:  [][]int{{1, 0, 1}, {0, 1, 1}},
+			wantOutput: "Paste the grid, one row per line of space separated ones or zeros, then a blank line.\n" +
+				"You entered 2 rows and 3 columns.\n1: 1 0 1\n2: 0 1 1\nContinue (C) Edit a row (E) Retry (R) Cancel (X)? ",
+		},
+		{
+			// blank lines before the grid are skipped and an edit replaces the row
+			input: "\n1 0 1\n0 1 1\n\nE\n2\n1 1 1\nC\n",
+			want:  [][]int{{1, 0, 1}, {1, 1, 1}},
+		},
+		{
+			// a row number out of range and a row of the wrong length are asked for again
+			input: "1 0\n0 1\n\nE\n3\nR\n1\n1 1 1\nR\nx\nR\n1\n1 1\nC\n",
+			want:  [][]int{{1, 1}, {0, 1}},
+		},
+		{input: "1 0\n0\n\nR\n1 1\n\nC\n", want: [][]int{{1, 1}}},
+		{input: "1 0\n0 1\n\nR\n1 1\n\nC\n", want: [][]int{{1, 1}}},
+		{input: "1 0\n0 1\n\nX\n", err: errorUserTerminated},
+		{input: "1 2\n\nX\n", err: errorUserTerminated},
+		// the end of input ends the grid, but with nothing left to confirm it
+		{input: "1 0\n0 1", err: io.EOF},
+	}
+	for i, tc := range tt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var w bytes.Buffer
+			got, err := pasteGrid(bytes.NewBufferString(tc.input), &w, format{})
+			if err != tc.err {
+				t.Fatalf("want error %v got %v", tc.err, err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("want %v got %v", tc.want, got)
+			}
+			if tc.wantOutput != "" && w.String() != tc.wantOutput {
+				t.Fatalf("want %q got %q", tc.wantOutput, w.String())
+			}
+		})
+	}
+}
+
 func assertEqual(t *testing.T, got, want []int) {
 	if len(got) == len(want) {
 		for i := 0; i < len(got); i++ {